@@ -0,0 +1,318 @@
+// Package dnstest provides an in-process authoritative dns server for
+// deterministic resolver tests that do not depend on live resolvers.
+//
+//	srv, err := dnstest.NewServer(&dnstest.Zone{
+//		Origin: "example.com",
+//		Records: []dnstest.Record{
+//			{Name: "www", Type: "A", Value: "10.0.0.1"},
+//			{Name: "*.dev", Type: "CNAME", Value: "www"},
+//		},
+//	})
+//	defer srv.Close()
+//	// query srv.Addr (udp and tcp)
+package dnstest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// maxCNAMEChain is the maximum number of cnames followed while answering
+const maxCNAMEChain = 16
+
+// Server is an in-process authoritative dns server listening on udp and tcp
+type Server struct {
+	// Addr is the listening address (ip:port) of the server
+	Addr string
+
+	udp, tcp *dns.Server
+
+	mu          sync.RWMutex
+	zones       map[string][]dns.RR // origin => records
+	rcodes      map[string]int      // name => injected rcode
+	latency     time.Duration
+	nameLatency map[string]time.Duration
+
+	queries atomic.Int64
+}
+
+// NewServer starts a server on a random local port serving given zones
+func NewServer(zones ...*Zone) (*Server, error) {
+	s := &Server{
+		zones:       map[string][]dns.RR{},
+		rcodes:      map[string]int{},
+		nameLatency: map[string]time.Duration{},
+	}
+	for _, zone := range zones {
+		if err := s.AddZone(zone); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// start listens on the same random port for udp and tcp
+func (s *Server) start() error {
+	var lastErr error
+	for attempt := 0; attempt < 10; attempt++ {
+		pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		listener, err := net.Listen("tcp", pc.LocalAddr().String())
+		if err != nil {
+			// port already used for tcp, try another one
+			_ = pc.Close()
+			lastErr = err
+			continue
+		}
+		s.Addr = pc.LocalAddr().String()
+		s.udp = &dns.Server{PacketConn: pc, Handler: s}
+		s.tcp = &dns.Server{Listener: listener, Handler: s}
+		for _, server := range []*dns.Server{s.udp, s.tcp} {
+			started := make(chan struct{})
+			server.NotifyStartedFunc = func() { close(started) }
+			go func(server *dns.Server) {
+				_ = server.ActivateAndServe()
+			}(server)
+			<-started
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return errors.Join(s.udp.Shutdown(), s.tcp.Shutdown())
+}
+
+// AddZone adds (or replaces) a zone served by the server
+func (s *Server) AddZone(zone *Zone) error {
+	rrs, err := zone.resourceRecords()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.zones[dns.Fqdn(strings.ToLower(zone.Origin))] = rrs
+	return nil
+}
+
+// AddZoneText parses a zone in zone file format and adds it (see ParseZone)
+func (s *Server) AddZoneText(origin, text string) error {
+	zone, err := ParseZone(origin, text)
+	if err != nil {
+		return err
+	}
+	return s.AddZone(zone)
+}
+
+// SetRcode makes the server answer queries for name with given rcode
+// (ex: dns.RcodeNameError, dns.RcodeServerFailure). Use dns.RcodeSuccess to
+// remove the injected rcode.
+func (s *Server) SetRcode(name string, rcode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name = dns.Fqdn(strings.ToLower(name))
+	if rcode == dns.RcodeSuccess {
+		delete(s.rcodes, name)
+		return
+	}
+	s.rcodes[name] = rcode
+}
+
+// SetLatency delays all answers by given duration
+func (s *Server) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// SetNameLatency delays answers for name by given duration (overriding SetLatency)
+func (s *Server) SetNameLatency(name string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nameLatency[dns.Fqdn(strings.ToLower(name))] = latency
+}
+
+// Queries returns the number of queries received by the server
+func (s *Server) Queries() int64 {
+	return s.queries.Load()
+}
+
+// Resolver returns a resolver sending all queries to the server
+func (s *Server) Resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, s.Addr)
+		},
+	}
+}
+
+// ServeDNS implements dns.Handler
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	s.queries.Add(1)
+	msg := new(dns.Msg)
+	msg.SetReply(req)
+	msg.Authoritative = true
+
+	if len(req.Question) == 1 {
+		q := req.Question[0]
+		name := strings.ToLower(q.Name)
+		s.mu.RLock()
+		latency, ok := s.nameLatency[name]
+		if !ok {
+			latency = s.latency
+		}
+		rcode, injected := s.rcodes[name]
+		s.mu.RUnlock()
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+		if injected {
+			msg.Rcode = rcode
+		} else {
+			s.answer(msg, name, q.Qtype)
+		}
+	} else {
+		msg.Rcode = dns.RcodeFormatError
+	}
+	_ = w.WriteMsg(msg)
+}
+
+// answer fills the message with the answers of name following cname chains
+func (s *Server) answer(msg *dns.Msg, name string, qtype uint16) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	origin, rrs := s.zone(name)
+	if origin == "" {
+		msg.Authoritative = false
+		msg.Rcode = dns.RcodeRefused
+		return
+	}
+	for i := 0; i < maxCNAMEChain; i++ {
+		records, exists := lookupName(rrs, name, origin)
+		if !exists {
+			// only the first name decides the rcode
+			if i == 0 {
+				msg.Rcode = dns.RcodeNameError
+			}
+			msg.Ns = append(msg.Ns, soa(rrs, origin))
+			return
+		}
+		var cname *dns.CNAME
+		found := false
+		for _, rr := range records {
+			if rr.Header().Rrtype == qtype || qtype == dns.TypeANY {
+				msg.Answer = append(msg.Answer, rr)
+				found = true
+			}
+			if c, ok := rr.(*dns.CNAME); ok {
+				cname = c
+			}
+		}
+		if found || cname == nil {
+			if !found {
+				// nodata
+				msg.Ns = append(msg.Ns, soa(rrs, origin))
+			}
+			return
+		}
+		msg.Answer = append(msg.Answer, cname)
+		name = strings.ToLower(cname.Target)
+		if origin, rrs = s.zone(name); origin == "" {
+			// target is not served by this server
+			return
+		}
+	}
+}
+
+// zone returns the most specific zone containing name
+func (s *Server) zone(name string) (string, []dns.RR) {
+	var origin string
+	for candidate := range s.zones {
+		if dns.IsSubDomain(candidate, name) && len(candidate) > len(origin) {
+			origin = candidate
+		}
+	}
+	return origin, s.zones[origin]
+}
+
+// lookupName returns the records of name (synthesized from the closest
+// wildcard if name does not exist) and whether the name exists
+func lookupName(rrs []dns.RR, name, origin string) ([]dns.RR, bool) {
+	if records, exists := ownedRecords(rrs, name); exists {
+		return records, true
+	}
+	// wildcard synthesis (RFC 4592): only the wildcard child of the closest
+	// encloser (the longest existing ancestor) can match
+	labels := dns.SplitDomainName(name)
+	for i := 1; i < len(labels); i++ {
+		encloser := dns.Fqdn(strings.Join(labels[i:], "."))
+		if !dns.IsSubDomain(origin, encloser) {
+			break
+		}
+		if _, exists := ownedRecords(rrs, encloser); !exists && encloser != origin {
+			continue
+		}
+		wildcard, _ := ownedRecords(rrs, "*."+encloser)
+		records := make([]dns.RR, 0, len(wildcard))
+		for _, rr := range wildcard {
+			synthesized := dns.Copy(rr)
+			synthesized.Header().Name = name
+			records = append(records, synthesized)
+		}
+		return records, len(records) > 0
+	}
+	return nil, false
+}
+
+// ownedRecords returns the records owned by name and whether the name exists
+// (names without records exist if they have descendants)
+func ownedRecords(rrs []dns.RR, name string) ([]dns.RR, bool) {
+	var records []dns.RR
+	exists := false
+	for _, rr := range rrs {
+		owner := rr.Header().Name
+		if owner == name {
+			records = append(records, rr)
+			exists = true
+		} else if strings.HasSuffix(owner, "."+name) {
+			exists = true
+		}
+	}
+	return records, exists
+}
+
+// soa returns the soa record of the zone (a default one if missing)
+func soa(rrs []dns.RR, origin string) dns.RR {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeSOA && rr.Header().Name == origin {
+			return rr
+		}
+	}
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: DefaultTTL},
+		Ns:      "ns." + origin,
+		Mbox:    "hostmaster." + origin,
+		Serial:  1,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  DefaultTTL,
+	}
+}
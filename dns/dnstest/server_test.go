@@ -0,0 +1,163 @@
+package dnstest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv, err := NewServer(&Zone{
+		Origin: "example.com",
+		Records: []Record{
+			{Name: "@", Type: "A", Value: "10.0.0.1"},
+			{Name: "www", Type: "A", Value: "10.0.0.2"},
+			{Name: "www", Type: "AAAA", Value: "::2"},
+			{Name: "alias", Type: "CNAME", Value: "alias2"},
+			{Name: "alias2", Type: "CNAME", Value: "www.example.com."},
+			{Name: "*.dev", Type: "A", Value: "10.0.0.3"},
+			{Name: "a.b.c", Type: "TXT", Value: `"deep"`},
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	return srv
+}
+
+func query(t *testing.T, srv *Server, network, name string, qtype uint16) *dns.Msg {
+	t.Helper()
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	client := &dns.Client{Net: network, Timeout: 5 * time.Second}
+	resp, _, err := client.Exchange(msg, srv.Addr)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestServerAnswers(t *testing.T) {
+	srv := newTestServer(t)
+
+	for _, network := range []string{"udp", "tcp"} {
+		resp := query(t, srv, network, "WWW.example.com", dns.TypeA)
+		require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+		require.True(t, resp.Authoritative)
+		require.Len(t, resp.Answer, 1)
+		require.Equal(t, "10.0.0.2", resp.Answer[0].(*dns.A).A.String())
+	}
+
+	resp := query(t, srv, "udp", "example.com", dns.TypeA)
+	require.Len(t, resp.Answer, 1)
+
+	// nodata
+	resp = query(t, srv, "udp", "www.example.com", dns.TypeMX)
+	require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	require.Empty(t, resp.Answer)
+	require.Len(t, resp.Ns, 1)
+	require.Equal(t, dns.TypeSOA, resp.Ns[0].Header().Rrtype)
+
+	// empty non-terminal
+	resp = query(t, srv, "udp", "b.c.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	require.Empty(t, resp.Answer)
+
+	resp = query(t, srv, "udp", "missing.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, resp.Rcode)
+	require.Len(t, resp.Ns, 1)
+
+	// not authoritative
+	resp = query(t, srv, "udp", "example.org", dns.TypeA)
+	require.Equal(t, dns.RcodeRefused, resp.Rcode)
+}
+
+func TestServerWildcardAndCNAME(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp := query(t, srv, "udp", "anything.dev.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	require.Len(t, resp.Answer, 1)
+	require.Equal(t, "anything.dev.example.com.", resp.Answer[0].Header().Name)
+	require.Equal(t, "10.0.0.3", resp.Answer[0].(*dns.A).A.String())
+
+	resp = query(t, srv, "udp", "x.y.dev.example.com", dns.TypeA)
+	require.Len(t, resp.Answer, 1)
+
+	// wildcard does not apply to siblings of its parent
+	resp = query(t, srv, "udp", "x.prod.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, resp.Rcode)
+
+	resp = query(t, srv, "udp", "alias.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+	require.Len(t, resp.Answer, 3)
+	require.Equal(t, "alias2.example.com.", resp.Answer[0].(*dns.CNAME).Target)
+	require.Equal(t, "www.example.com.", resp.Answer[1].(*dns.CNAME).Target)
+	require.Equal(t, "10.0.0.2", resp.Answer[2].(*dns.A).A.String())
+
+	resp = query(t, srv, "udp", "alias.example.com", dns.TypeCNAME)
+	require.Len(t, resp.Answer, 1)
+}
+
+func TestServerInjection(t *testing.T) {
+	srv := newTestServer(t)
+
+	srv.SetRcode("www.example.com", dns.RcodeServerFailure)
+	resp := query(t, srv, "udp", "www.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeServerFailure, resp.Rcode)
+	srv.SetRcode("www.example.com", dns.RcodeSuccess)
+	resp = query(t, srv, "udp", "www.example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeSuccess, resp.Rcode)
+
+	srv.SetRcode("example.com", dns.RcodeNameError)
+	resp = query(t, srv, "udp", "example.com", dns.TypeA)
+	require.Equal(t, dns.RcodeNameError, resp.Rcode)
+
+	srv.SetNameLatency("www.example.com", 200*time.Millisecond)
+	start := time.Now()
+	_ = query(t, srv, "udp", "www.example.com", dns.TypeA)
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	require.Positive(t, srv.Queries())
+}
+
+func TestServerZoneText(t *testing.T) {
+	srv := newTestServer(t)
+	err := srv.AddZoneText("example.net", `
+@        3600 IN SOA ns1.example.net. admin.example.net. 2024010101 7200 3600 1209600 300
+www           IN A   192.0.2.1
+*.wild        IN A   192.0.2.2
+mail          IN MX  10 www.example.net.
+`)
+	require.NoError(t, err)
+
+	resolver := srv.Resolver()
+	ips, err := resolver.LookupHost(context.Background(), "www.example.net")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.1"}, ips)
+
+	ips, err = resolver.LookupHost(context.Background(), "random.wild.example.net")
+	require.NoError(t, err)
+	require.Equal(t, []string{"192.0.2.2"}, ips)
+
+	mxs, err := resolver.LookupMX(context.Background(), "mail.example.net")
+	require.NoError(t, err)
+	require.Len(t, mxs, 1)
+
+	_, err = resolver.LookupHost(context.Background(), "missing.example.net")
+	var dnsErr *net.DNSError
+	require.ErrorAs(t, err, &dnsErr)
+	require.True(t, dnsErr.IsNotFound)
+
+	resp := query(t, srv, "udp", "missing.example.net", dns.TypeA)
+	require.Equal(t, uint32(2024010101), resp.Ns[0].(*dns.SOA).Serial)
+
+	_, err = ParseZone("example.net", "www IN A not-an-ip")
+	require.Error(t, err)
+	_, err = NewServer(&Zone{Origin: "example.net", Records: []Record{{Name: "www", Type: "A", Value: "not-an-ip"}}})
+	require.Error(t, err)
+}
@@ -0,0 +1,90 @@
+package dnstest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultTTL is the ttl of records without an explicit ttl
+const DefaultTTL = 300
+
+// Record is a resource record of a zone
+type Record struct {
+	// Name is the owner name, relative to the zone origin (ex: "www", "*.dev")
+	// or fully qualified (ending with a dot). "@" or empty is the zone apex.
+	Name string
+	// Type is the record type (ex: A, AAAA, CNAME, TXT, MX)
+	Type string
+	// Value is the record data in presentation format (ex: "10 mx.example.com.").
+	// Names without a trailing dot are relative to the zone origin.
+	Value string
+	// TTL of the record (DefaultTTL if zero)
+	TTL uint32
+}
+
+// Zone is an authoritative zone served by the Server
+type Zone struct {
+	// Origin is the name of the zone (ex: example.com)
+	Origin  string
+	Records []Record
+
+	rrs []dns.RR
+}
+
+// ParseZone parses a zone in zone file format (RFC 1035). Relative names are
+// relative to origin unless an $ORIGIN directive is present.
+func ParseZone(origin, text string) (*Zone, error) {
+	zone := &Zone{Origin: origin}
+	parser := dns.NewZoneParser(strings.NewReader(text), dns.Fqdn(origin), "")
+	parser.SetDefaultTTL(DefaultTTL)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		zone.rrs = append(zone.rrs, rr)
+	}
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// resourceRecords returns the records of the zone as lowercase dns.RR
+func (z *Zone) resourceRecords() ([]dns.RR, error) {
+	origin := dns.Fqdn(strings.ToLower(z.Origin))
+	rrs := make([]dns.RR, 0, len(z.rrs)+len(z.Records))
+	rrs = append(rrs, z.rrs...)
+	for _, record := range z.Records {
+		ttl := record.TTL
+		if ttl == 0 {
+			ttl = DefaultTTL
+		}
+		// relative names in values (ex: cname targets) are relative to the origin
+		line := fmt.Sprintf("%s %d IN %s %s", ownerName(record.Name, origin), ttl, strings.ToUpper(record.Type), record.Value)
+		parser := dns.NewZoneParser(strings.NewReader(line), origin, "")
+		rr, ok := parser.Next()
+		if err := parser.Err(); err != nil {
+			return nil, fmt.Errorf("invalid record %s %s %s: %w", record.Name, record.Type, record.Value, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid record %s %s: empty", record.Name, record.Type)
+		}
+		rrs = append(rrs, rr)
+	}
+	for _, rr := range rrs {
+		rr.Header().Name = strings.ToLower(rr.Header().Name)
+	}
+	return rrs, nil
+}
+
+// ownerName returns the fully qualified owner name of a record
+func ownerName(name, origin string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "" || name == "@":
+		return origin
+	case dns.IsFqdn(name):
+		return name
+	default:
+		return name + "." + origin
+	}
+}
@@ -0,0 +1,136 @@
+// Package domain provides domain name utilities: public suffix (eTLD+1)
+// parsing, subdomain levels, IDN conversion, label validation, reverse dns
+// names and a trie for scope checks.
+package domain
+
+import (
+	"net"
+	"strings"
+
+	"github.com/projectdiscovery/utils/errkit"
+	"golang.org/x/net/publicsuffix"
+)
+
+var (
+	// ErrInvalidName is returned for names which are not valid domain names
+	ErrInvalidName = errkit.New("invalid domain name")
+	// ErrInvalidLabel is returned for labels which are not valid per RFC 1035/5890
+	ErrInvalidLabel = errkit.New("invalid domain label")
+	// ErrNoRegistrableDomain is returned for names without registrable domain
+	// (ex: public suffixes like co.uk or ip addresses)
+	ErrNoRegistrableDomain = errkit.New("name has no registrable domain")
+)
+
+// Name is a domain name split into its public suffix components
+//
+// For example "www.api.example.co.uk" is parsed as:
+//   - Subdomain: "www.api"
+//   - Domain: "example.co.uk"
+//   - Suffix: "co.uk"
+type Name struct {
+	// Host is the normalized (lowercase, ascii) name
+	Host string
+	// Subdomain are the labels below the registrable domain (empty for the apex)
+	Subdomain string
+	// Domain is the registrable domain (eTLD+1)
+	Domain string
+	// Suffix is the public suffix (eTLD)
+	Suffix string
+	// ICANN is true if the suffix is managed by ICANN, false for privately
+	// managed suffixes (ex: github.io) or unknown top level domains
+	ICANN bool
+}
+
+// Parse normalizes and splits a domain name into its public suffix components
+func Parse(host string) (*Name, error) {
+	host, err := Normalize(host)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return nil, errkit.With(ErrNoRegistrableDomain, "host", host)
+	}
+	suffix, icann := publicsuffix.PublicSuffix(host)
+	if suffix == host {
+		return nil, errkit.With(ErrNoRegistrableDomain, "host", host)
+	}
+	rest := strings.TrimSuffix(host, "."+suffix)
+	name := &Name{Host: host, Suffix: suffix, ICANN: icann}
+	if idx := strings.LastIndexByte(rest, '.'); idx >= 0 {
+		name.Subdomain = rest[:idx]
+		name.Domain = rest[idx+1:] + "." + suffix
+	} else {
+		name.Domain = rest + "." + suffix
+	}
+	return name, nil
+}
+
+// Level returns the number of subdomain labels (0 for the registrable domain)
+func (n *Name) Level() int {
+	if n.Subdomain == "" {
+		return 0
+	}
+	return strings.Count(n.Subdomain, ".") + 1
+}
+
+// Levels returns the names from the registrable domain down to the host
+//
+// For example "a.b.example.com" returns
+// ["example.com", "b.example.com", "a.b.example.com"]
+func (n *Name) Levels() []string {
+	levels := make([]string, 0, n.Level()+1)
+	levels = append(levels, n.Domain)
+	name := n.Domain
+	labels := strings.Split(n.Subdomain, ".")
+	for i := len(labels) - 1; i >= 0 && n.Subdomain != ""; i-- {
+		name = labels[i] + "." + name
+		levels = append(levels, name)
+	}
+	return levels
+}
+
+// EffectiveTLDPlusOne returns the registrable domain (eTLD+1) of host
+func EffectiveTLDPlusOne(host string) (string, error) {
+	name, err := Parse(host)
+	if err != nil {
+		return "", err
+	}
+	return name.Domain, nil
+}
+
+// PublicSuffix returns the public suffix (eTLD) of host and whether it is
+// managed by ICANN
+func PublicSuffix(host string) (string, bool, error) {
+	host, err := Normalize(host)
+	if err != nil {
+		return "", false, err
+	}
+	suffix, icann := publicsuffix.PublicSuffix(host)
+	return suffix, icann, nil
+}
+
+// Levels returns the names from the registrable domain down to host (see Name.Levels)
+func Levels(host string) ([]string, error) {
+	name, err := Parse(host)
+	if err != nil {
+		return nil, err
+	}
+	return name.Levels(), nil
+}
+
+// Normalize trims spaces and the trailing dot of host and converts it to
+// lowercase ascii (punycode). Names with empty labels are rejected.
+func Normalize(host string) (string, error) {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if host == "" {
+		return "", errkit.With(ErrInvalidName, "reason", "empty name")
+	}
+	ascii, err := ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(ascii, ".") || strings.HasSuffix(ascii, ".") || strings.Contains(ascii, "..") {
+		return "", errkit.With(ErrInvalidName, "name", host, "reason", "empty label")
+	}
+	return ascii, nil
+}
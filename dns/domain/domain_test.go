@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/projectdiscovery/utils/errkit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		host      string
+		subdomain string
+		domain    string
+		suffix    string
+		icann     bool
+		level     int
+	}{
+		{"example.com", "", "example.com", "com", true, 0},
+		{"WWW.Example.COM.", "www", "example.com", "com", true, 1},
+		{"a.b.example.co.uk", "a.b", "example.co.uk", "co.uk", true, 2},
+		{"foo.bar.github.io", "foo", "bar.github.io", "github.io", false, 1},
+		{"www.bücher.de", "www", "xn--bcher-kva.de", "de", true, 1},
+		{"host.example.unknowntld", "host", "example.unknowntld", "unknowntld", false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			name, err := Parse(test.host)
+			require.NoError(t, err)
+			require.Equal(t, test.subdomain, name.Subdomain)
+			require.Equal(t, test.domain, name.Domain)
+			require.Equal(t, test.suffix, name.Suffix)
+			require.Equal(t, test.icann, name.ICANN)
+			require.Equal(t, test.level, name.Level())
+		})
+	}
+
+	for _, host := range []string{"", "co.uk", "com", "192.0.2.1", "::1"} {
+		_, err := Parse(host)
+		require.Error(t, err, host)
+	}
+	for _, host := range []string{"a..b.com", ".a.com", "a.com.."} {
+		_, err := Parse(host)
+		require.True(t, errors.Is(err, ErrInvalidName), host)
+	}
+	_, err := EffectiveTLDPlusOne("co.uk")
+	require.True(t, errors.Is(err, ErrNoRegistrableDomain))
+
+	root, err := EffectiveTLDPlusOne("api.example.co.uk")
+	require.NoError(t, err)
+	require.Equal(t, "example.co.uk", root)
+
+	suffix, icann, err := PublicSuffix("api.example.co.uk")
+	require.NoError(t, err)
+	require.Equal(t, "co.uk", suffix)
+	require.True(t, icann)
+}
+
+func TestLevels(t *testing.T) {
+	levels, err := Levels("a.b.example.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com", "b.example.com", "a.b.example.com"}, levels)
+
+	levels, err = Levels("example.com")
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com"}, levels)
+}
+
+func TestIDN(t *testing.T) {
+	ascii, err := ToASCII("Bücher.Example")
+	require.NoError(t, err)
+	require.Equal(t, "xn--bcher-kva.example", ascii)
+
+	unicode, err := ToUnicode("xn--bcher-kva.example")
+	require.NoError(t, err)
+	require.Equal(t, "bücher.example", unicode)
+
+	// underscore labels are converted as is
+	ascii, err = ToASCII("_dmarc.Example.com")
+	require.NoError(t, err)
+	require.Equal(t, "_dmarc.example.com", ascii)
+
+	require.True(t, IsIDN("bücher.example"))
+	require.True(t, IsIDN("www.xn--bcher-kva.example"))
+	require.False(t, IsIDN("www.example.com"))
+
+	_, err = ToUnicode("xn--a.example")
+	require.Error(t, err)
+}
+
+func TestValidateName(t *testing.T) {
+	valid := []string{
+		"example.com",
+		"example.com.",
+		"a-b.example",
+		"123.example",
+		"xn--bcher-kva.example",
+		"bücher.example",
+		strings.Repeat("a", 63) + ".example",
+	}
+	for _, name := range valid {
+		require.NoError(t, ValidateName(name), name)
+	}
+
+	invalid := []string{
+		"",
+		".",
+		"a..example",
+		"-a.example",
+		"a-.example",
+		"_dmarc.example",
+		"a b.example",
+		"ab--cd.example",
+		"xn--a.example",
+		strings.Repeat("a", 64) + ".example",
+		strings.Repeat("a.", 127) + "ab",
+	}
+	for _, name := range invalid {
+		err := ValidateName(name)
+		require.Error(t, err, name)
+		require.True(t, errors.Is(err, ErrInvalidName), name)
+	}
+
+	require.True(t, errors.Is(ValidateLabel("a_b"), ErrInvalidLabel))
+	require.NoError(t, ValidateLabel("bücher"))
+	require.False(t, IsValidName("a..b"))
+}
+
+func TestValidateNameDoesNotMutateSentinel(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Error(t, ValidateName("-a.com"))
+			require.Error(t, ValidateName("a_b.com"))
+		}()
+	}
+	wg.Wait()
+
+	require.Empty(t, errkit.GetAttr(ErrInvalidName))
+	require.Empty(t, errkit.GetAttr(ErrInvalidLabel))
+	_, err := Parse("xn--zz.com")
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "leading or trailing hyphen")
+}
+
+func TestReverseName(t *testing.T) {
+	name, err := ReverseName("192.0.2.1")
+	require.NoError(t, err)
+	require.Equal(t, "1.2.0.192.in-addr.arpa", name)
+
+	name, err = ReverseName("2001:db8::1")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa", name)
+
+	_, err = ReverseName("not-an-ip")
+	require.Error(t, err)
+
+	for _, ip := range []string{"192.0.2.1", "2001:db8::1", "::1", "fe80::abcd:1234"} {
+		addr := netip.MustParseAddr(ip)
+		parsed, err := ParseReverseName(ReverseAddr(addr) + ".")
+		require.NoError(t, err)
+		require.Equal(t, addr, parsed)
+	}
+
+	prefix, err := ParseReverseZone("2.0.192.IN-ADDR.ARPA.")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("192.0.2.0/24"), prefix)
+
+	prefix, err = ParseReverseZone("8.b.d.0.1.0.0.2.ip6.arpa")
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::/32"), prefix)
+
+	for _, name := range []string{"2.0.192.in-addr.arpa", "example.com", "256.0.0.1.in-addr.arpa", "01.0.0.1.in-addr.arpa", "1.2.3.4.5.in-addr.arpa", "g.ip6.arpa", "10.ip6.arpa"} {
+		_, err := ParseReverseName(name)
+		require.True(t, errors.Is(err, ErrInvalidReverseName), name)
+	}
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/projectdiscovery/utils/errkit"
+	"golang.org/x/net/idna"
+)
+
+const (
+	// MaxLabelLength is the maximum length of a label in octets (RFC 1035)
+	MaxLabelLength = 63
+	// MaxNameLength is the maximum length of a name in presentation format
+	// without the trailing dot (RFC 1035)
+	MaxNameLength = 253
+
+	acePrefix = "xn--"
+)
+
+var (
+	// lookupProfile maps names per IDNA2008 (UTS #46) without enforcing
+	// hostname rules so that names like _dmarc.example.com are converted
+	lookupProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false), idna.Transitional(false))
+	// labelProfile validates A-labels (RFC 5890)
+	labelProfile = idna.New(idna.ValidateForRegistration())
+)
+
+// ToASCII converts a (possibly internationalized) name to lowercase ascii
+// using punycode for U-labels (ex: "bücher.example" => "xn--bcher-kva.example")
+func ToASCII(name string) (string, error) {
+	if isLowerASCII(name) {
+		return name, nil
+	}
+	ascii, err := lookupProfile.ToASCII(name)
+	if err != nil {
+		return "", wrapInvalid(ErrInvalidName, err, "name", name)
+	}
+	return ascii, nil
+}
+
+// ToUnicode converts the A-labels (punycode) of a name to unicode
+// (ex: "xn--bcher-kva.example" => "bücher.example")
+func ToUnicode(name string) (string, error) {
+	if !strings.Contains(strings.ToLower(name), acePrefix) {
+		return strings.ToLower(name), nil
+	}
+	unicode, err := lookupProfile.ToUnicode(name)
+	if err != nil {
+		return "", wrapInvalid(ErrInvalidName, err, "name", name)
+	}
+	return unicode, nil
+}
+
+// IsIDN returns true if name contains U-labels or A-labels
+func IsIDN(name string) bool {
+	return !isASCII(name) || strings.HasPrefix(strings.ToLower(name), acePrefix) || strings.Contains(strings.ToLower(name), "."+acePrefix)
+}
+
+// ValidateLabel validates a label per RFC 1035 (letters, digits and hyphens,
+// 1 to 63 octets, no leading or trailing hyphen) and RFC 5890 (labels with
+// hyphens in the third and fourth position must be valid A-labels).
+// U-labels are validated in their ascii form.
+func ValidateLabel(label string) error {
+	if !isASCII(label) {
+		ascii, err := labelProfile.ToASCII(label)
+		if err != nil {
+			return wrapInvalid(ErrInvalidLabel, err, "label", label)
+		}
+		if strings.Contains(ascii, ".") {
+			return errkit.With(ErrInvalidLabel, "label", label, "reason", "label contains a dot")
+		}
+		label = ascii
+	}
+	switch {
+	case label == "":
+		return errkit.With(ErrInvalidLabel, "reason", "empty label")
+	case len(label) > MaxLabelLength:
+		return errkit.With(ErrInvalidLabel, "label", label, "reason", "label too long")
+	case label[0] == '-' || label[len(label)-1] == '-':
+		return errkit.With(ErrInvalidLabel, "label", label, "reason", "leading or trailing hyphen")
+	}
+	for i := 0; i < len(label); i++ {
+		if !isLDH(label[i]) {
+			return errkit.With(ErrInvalidLabel, "label", label, "reason", "invalid character")
+		}
+	}
+	if len(label) >= 4 && label[2:4] == "--" {
+		if !strings.EqualFold(label[:4], acePrefix) {
+			return errkit.With(ErrInvalidLabel, "label", label, "reason", "reserved label")
+		}
+		if _, err := labelProfile.ToUnicode(strings.ToLower(label)); err != nil {
+			return wrapInvalid(ErrInvalidLabel, err, "label", label, "reason", "invalid a-label")
+		}
+	}
+	return nil
+}
+
+// ValidateName validates each label of a name (see ValidateLabel) and the
+// length of the name. A single trailing dot (fully qualified name) is allowed.
+func ValidateName(name string) error {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return errkit.With(ErrInvalidName, "reason", "empty name")
+	}
+	ascii := name
+	if !isASCII(name) {
+		var err error
+		if ascii, err = labelProfile.ToASCII(name); err != nil {
+			return wrapInvalid(ErrInvalidName, err, "name", name)
+		}
+	}
+	if len(ascii) > MaxNameLength {
+		return errkit.With(ErrInvalidName, "name", name, "reason", "name too long")
+	}
+	for _, label := range strings.Split(ascii, ".") {
+		if err := ValidateLabel(label); err != nil {
+			return wrapInvalid(ErrInvalidName, err, "name", name)
+		}
+	}
+	return nil
+}
+
+// IsValidName returns true if name is a valid domain name (see ValidateName)
+func IsValidName(name string) bool {
+	return ValidateName(name) == nil
+}
+
+// wrapInvalid returns a new error for sentinel with given attributes that
+// wraps err. sentinel must not be the first error given to errkit.Append
+// since the result would share (and mutate) the record of the sentinel
+func wrapInvalid(sentinel error, err error, args ...any) error {
+	return errkit.Append(errkit.With(sentinel, args...), err)
+}
+
+func isLDH(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isLowerASCII returns true if s is lowercase ascii without A-labels
+// (which are validated by the idna profile)
+func isLowerASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf || s[i] >= 'A' && s[i] <= 'Z' {
+			return false
+		}
+	}
+	return !strings.Contains(s, acePrefix)
+}
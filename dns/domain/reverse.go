@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+const (
+	// ReverseZoneV4 is the reverse dns zone of ipv4 addresses
+	ReverseZoneV4 = "in-addr.arpa"
+	// ReverseZoneV6 is the reverse dns zone of ipv6 addresses
+	ReverseZoneV6 = "ip6.arpa"
+
+	hexDigits = "0123456789abcdef"
+)
+
+// ErrInvalidReverseName is returned for names which are not reverse dns names
+var ErrInvalidReverseName = errkit.New("invalid reverse dns name")
+
+// ReverseName returns the reverse dns (PTR) name of an ip address
+// (ex: "192.0.2.1" => "1.2.0.192.in-addr.arpa")
+func ReverseName(ip string) (string, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	return ReverseAddr(addr), nil
+}
+
+// ReverseAddr returns the reverse dns (PTR) name of an address. IPv4-mapped
+// ipv6 addresses use the in-addr.arpa zone.
+func ReverseAddr(addr netip.Addr) string {
+	addr = addr.Unmap()
+	var sb strings.Builder
+	if addr.Is4() {
+		octets := addr.As4()
+		for i := len(octets) - 1; i >= 0; i-- {
+			sb.WriteString(strconv.Itoa(int(octets[i])))
+			sb.WriteByte('.')
+		}
+		sb.WriteString(ReverseZoneV4)
+		return sb.String()
+	}
+	octets := addr.As16()
+	sb.Grow(len(octets)*4 + len(ReverseZoneV6))
+	for i := len(octets) - 1; i >= 0; i-- {
+		sb.WriteByte(hexDigits[octets[i]&0x0f])
+		sb.WriteByte('.')
+		sb.WriteByte(hexDigits[octets[i]>>4])
+		sb.WriteByte('.')
+	}
+	sb.WriteString(ReverseZoneV6)
+	return sb.String()
+}
+
+// ParseReverseName returns the address of a reverse dns (PTR) name
+// (ex: "1.2.0.192.in-addr.arpa" => 192.0.2.1)
+func ParseReverseName(name string) (netip.Addr, error) {
+	prefix, err := ParseReverseZone(name)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !prefix.IsSingleIP() {
+		return netip.Addr{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "partial address")
+	}
+	return prefix.Addr(), nil
+}
+
+// ParseReverseZone returns the network of a (possibly partial) reverse dns
+// name (ex: "2.0.192.in-addr.arpa" => 192.0.2.0/24, "8.b.d.0.1.0.0.2.ip6.arpa"
+// => 2001:db8::/32). Full names return single address prefixes.
+func ParseReverseZone(name string) (netip.Prefix, error) {
+	name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+	switch {
+	case strings.HasSuffix(name, "."+ReverseZoneV4):
+		return parseReverseV4(name, strings.TrimSuffix(name, "."+ReverseZoneV4))
+	case strings.HasSuffix(name, "."+ReverseZoneV6):
+		return parseReverseV6(name, strings.TrimSuffix(name, "."+ReverseZoneV6))
+	default:
+		return netip.Prefix{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "unknown zone")
+	}
+}
+
+func parseReverseV4(name, labels string) (netip.Prefix, error) {
+	parts := strings.Split(labels, ".")
+	if len(parts) > 4 {
+		return netip.Prefix{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "too many labels")
+	}
+	var octets [4]byte
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 8)
+		if err != nil || (len(part) > 1 && part[0] == '0') {
+			return netip.Prefix{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "invalid octet")
+		}
+		octets[len(parts)-1-i] = byte(value)
+	}
+	return netip.PrefixFrom(netip.AddrFrom4(octets), len(parts)*8), nil
+}
+
+func parseReverseV6(name, labels string) (netip.Prefix, error) {
+	parts := strings.Split(labels, ".")
+	if len(parts) > 32 {
+		return netip.Prefix{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "too many labels")
+	}
+	var octets [16]byte
+	for i, part := range parts {
+		if len(part) != 1 || strings.IndexByte(hexDigits, part[0]) < 0 {
+			return netip.Prefix{}, errkit.With(ErrInvalidReverseName, "name", name, "reason", "invalid nibble")
+		}
+		nibble := byte(strings.IndexByte(hexDigits, part[0]))
+		// position of the nibble from the most significant one
+		pos := len(parts) - 1 - i
+		if pos%2 == 0 {
+			octets[pos/2] |= nibble << 4
+		} else {
+			octets[pos/2] |= nibble
+		}
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(octets), len(parts)*4), nil
+}
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"strings"
+	"sync"
+)
+
+// Trie is a label trie of root domains used to check whether hosts are in
+// scope (equal to or subdomain of a root domain). Lookups do not allocate for
+// lowercase ascii hosts and are safe for concurrent use.
+type Trie struct {
+	mu   sync.RWMutex
+	root *trieNode
+	size int
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	// domain is the root domain ending at this node (empty if none)
+	domain string
+}
+
+// NewTrie creates a trie containing given root domains
+func NewTrie(domains ...string) (*Trie, error) {
+	t := &Trie{root: &trieNode{}}
+	for _, domain := range domains {
+		if err := t.Add(domain); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Add adds a root domain to the trie. A leading "*." is ignored since
+// subdomains of root domains are always in scope.
+func (t *Trie) Add(domain string) error {
+	domain, err := Normalize(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	node := t.root
+	for name := domain; name != ""; {
+		var label string
+		label, name = lastLabel(name)
+		child, ok := node.children[label]
+		if !ok {
+			if node.children == nil {
+				node.children = map[string]*trieNode{}
+			}
+			child = &trieNode{}
+			node.children[label] = child
+		}
+		node = child
+	}
+	if node.domain == "" {
+		node.domain = domain
+		t.size++
+	}
+	return nil
+}
+
+// Remove removes a root domain from the trie and returns true if it was present
+func (t *Trie) Remove(domain string) bool {
+	domain, err := Normalize(strings.TrimPrefix(strings.TrimSpace(domain), "*."))
+	if err != nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	path := []*trieNode{t.root}
+	labels := []string{}
+	node := t.root
+	for name := domain; name != ""; {
+		var label string
+		label, name = lastLabel(name)
+		child, ok := node.children[label]
+		if !ok {
+			return false
+		}
+		path = append(path, child)
+		labels = append(labels, label)
+		node = child
+	}
+	if node.domain == "" {
+		return false
+	}
+	node.domain = ""
+	t.size--
+	// prune empty nodes
+	for i := len(path) - 1; i > 0 && path[i].domain == "" && len(path[i].children) == 0; i-- {
+		delete(path[i-1].children, labels[i-1])
+	}
+	return true
+}
+
+// Match returns the shortest root domain containing host
+func (t *Trie) Match(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if !isLowerASCII(host) {
+		var err error
+		if host, err = ToASCII(host); err != nil {
+			return "", false
+		}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	node := t.root
+	for name := host; name != ""; {
+		var label string
+		label, name = lastLabel(name)
+		child, ok := node.children[label]
+		if !ok {
+			return "", false
+		}
+		if child.domain != "" {
+			return child.domain, true
+		}
+		node = child
+	}
+	return "", false
+}
+
+// Contains returns true if host is equal to or a subdomain of a root domain
+func (t *Trie) Contains(host string) bool {
+	_, ok := t.Match(host)
+	return ok
+}
+
+// Len returns the number of root domains in the trie
+func (t *Trie) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.size
+}
+
+// lastLabel splits name into its last label and the remaining name
+func lastLabel(name string) (string, string) {
+	idx := strings.LastIndexByte(name, '.')
+	if idx < 0 {
+		return name, ""
+	}
+	return name[idx+1:], name[:idx]
+}
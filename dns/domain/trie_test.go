@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrie(t *testing.T) {
+	trie, err := NewTrie("example.com", "*.example.org", "api.example.net", "Bücher.de")
+	require.NoError(t, err)
+	require.Equal(t, 4, trie.Len())
+
+	tests := []struct {
+		host   string
+		root   string
+		inside bool
+	}{
+		{"example.com", "example.com", true},
+		{"WWW.example.com.", "example.com", true},
+		{"a.b.example.org", "example.org", true},
+		{"v1.api.example.net", "api.example.net", true},
+		{"www.bücher.de", "xn--bcher-kva.de", true},
+		{"www.xn--bcher-kva.de", "xn--bcher-kva.de", true},
+		{"example.net", "", false},
+		{"www.example.net", "", false},
+		{"notexample.com", "", false},
+		{"com", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		root, ok := trie.Match(test.host)
+		require.Equal(t, test.inside, ok, test.host)
+		require.Equal(t, test.root, root, test.host)
+		require.Equal(t, test.inside, trie.Contains(test.host), test.host)
+	}
+
+	// the shortest root wins
+	require.NoError(t, trie.Add("example.net"))
+	root, ok := trie.Match("v1.api.example.net")
+	require.True(t, ok)
+	require.Equal(t, "example.net", root)
+
+	require.True(t, trie.Remove("example.net"))
+	require.False(t, trie.Remove("example.net"))
+	require.False(t, trie.Remove("missing.com"))
+	require.False(t, trie.Contains("www.example.net"))
+	require.True(t, trie.Contains("v1.api.example.net"))
+	require.True(t, trie.Remove("api.example.net"))
+	require.False(t, trie.Contains("v1.api.example.net"))
+	require.Equal(t, 3, trie.Len())
+
+	_, err = NewTrie("example.com", " ")
+	require.Error(t, err)
+}
+
+func BenchmarkTrieContains(b *testing.B) {
+	trie, _ := NewTrie()
+	for i := 0; i < 10000; i++ {
+		_ = trie.Add(fmt.Sprintf("example%d.com", i))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trie.Contains("a.b.c.example5000.com")
+	}
+}
@@ -0,0 +1,202 @@
+package wildcard
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/hmap/store/hybrid"
+)
+
+// CacheEntry is the probe result of a wildcard level (ex: *.example.com)
+type CacheEntry struct {
+	// IPs are the wildcard answers of the level, empty if the level is not a wildcard
+	IPs []string `json:"ips,omitempty"`
+	// CNAMEs are the cname terminals of the wildcard answers
+	CNAMEs []string `json:"cnames,omitempty"`
+	// Records are other wildcard records ("TYPE value")
+	Records   []string  `json:"records,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsWildcard returns true if the level resolved to wildcard answers
+func (c CacheEntry) IsWildcard() bool {
+	return len(c.IPs) > 0 || len(c.CNAMEs) > 0 || len(c.Records) > 0
+}
+
+// Cache is a pluggable store of wildcard probe results keyed by level
+type Cache interface {
+	// Get returns the entry of given level
+	Get(level string) (CacheEntry, bool)
+	// Set stores the entry of given level
+	Set(level string, entry CacheEntry) error
+	// Delete removes the entry of given level
+	Delete(level string) error
+	// Iterate calls fn for each entry until it returns an error
+	Iterate(fn func(level string, entry CacheEntry) error) error
+	// Close releases the resources of the cache
+	Close() error
+}
+
+// MemoryCache is an in-memory Cache which can be shared by resolvers
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]CacheEntry
+}
+
+// NewMemoryCache creates a new in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]CacheEntry{}}
+}
+
+// Get returns the entry of given level
+func (m *MemoryCache) Get(level string) (CacheEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[level]
+	return entry, ok
+}
+
+// Set stores the entry of given level
+func (m *MemoryCache) Set(level string, entry CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[level] = entry
+	return nil
+}
+
+// Delete removes the entry of given level
+func (m *MemoryCache) Delete(level string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, level)
+	return nil
+}
+
+// Iterate calls fn for each entry until it returns an error
+func (m *MemoryCache) Iterate(fn func(level string, entry CacheEntry) error) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for level, entry := range m.entries {
+		if err := fn(level, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op for the in-memory cache
+func (m *MemoryCache) Close() error {
+	return nil
+}
+
+// DiskCache is an on-disk (leveldb) Cache persisting probe results across runs
+type DiskCache struct {
+	storage *hybrid.HybridMap
+}
+
+// NewDiskCache opens (or creates) a leveldb cache in given directory.
+// If path is empty a temporary directory removed on Close is used.
+func NewDiskCache(path string) (*DiskCache, error) {
+	options := hybrid.DefaultDiskOptions
+	if path != "" {
+		if err := os.MkdirAll(path, 0700); err != nil {
+			return nil, err
+		}
+		options.Path = path
+		options.Cleanup = false
+	}
+	storage, err := hybrid.New(options)
+	if err != nil {
+		return nil, err
+	}
+	return &DiskCache{storage: storage}, nil
+}
+
+// Get returns the entry of given level
+func (d *DiskCache) Get(level string) (CacheEntry, bool) {
+	data, ok := d.storage.Get(level)
+	if !ok {
+		return CacheEntry{}, false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// Set stores the entry of given level
+func (d *DiskCache) Set(level string, entry CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return d.storage.Set(level, data)
+}
+
+// Delete removes the entry of given level
+func (d *DiskCache) Delete(level string) error {
+	return d.storage.Del(level)
+}
+
+// Iterate calls fn for each entry until it returns an error
+func (d *DiskCache) Iterate(fn func(level string, entry CacheEntry) error) error {
+	var iterErr error
+	d.storage.Scan(func(k, v []byte) error {
+		var entry CacheEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return nil
+		}
+		iterErr = fn(string(k), entry)
+		return iterErr
+	})
+	return iterErr
+}
+
+// Close closes the underlying database
+func (d *DiskCache) Close() error {
+	return d.storage.Close()
+}
+
+// Snapshot contains the probe results of a resolver which can be shared
+// with other resolvers (ex: distributed workers)
+type Snapshot struct {
+	CreatedAt time.Time             `json:"created_at"`
+	Levels    map[string]CacheEntry `json:"levels"`
+}
+
+// WildcardIPs returns all wildcard ips of the snapshot (see Resolver.GetAllWildcardIPs)
+func (s *Snapshot) WildcardIPs() map[string]struct{} {
+	ips := make(map[string]struct{})
+	for _, entry := range s.Levels {
+		for _, ip := range entry.IPs {
+			ips[ip] = struct{}{}
+		}
+	}
+	return ips
+}
+
+// WriteTo writes the snapshot as json
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadSnapshot reads a json snapshot written by Snapshot.WriteTo
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Levels == nil {
+		snapshot.Levels = map[string]CacheEntry{}
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,192 @@
+package wildcard
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingResolver returns a resolver where *.example.com is a wildcard
+// zone (1.1.1.1) and *.example.org is a normal zone
+func newCountingResolver(probes *atomic.Int32) *Resolver {
+	return NewResolver([]string{"example.com", "example.org"}, func(host string) ([]string, error) {
+		probes.Add(1)
+		if strings.HasSuffix(host, ".example.com") {
+			return []string{"1.1.1.1"}, nil
+		}
+		return nil, nil
+	})
+}
+
+func TestResolverDiskCache(t *testing.T) {
+	dir := t.TempDir()
+
+	var probes atomic.Int32
+	cache, err := NewDiskCache(dir)
+	require.NoError(t, err)
+	resolver := newCountingResolver(&probes)
+	resolver.SetCache(cache)
+	isWildcard, _ := resolver.LookupHost("www.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	isWildcard, _ = resolver.LookupHost("www.example.org", []string{"2.2.2.2"})
+	require.False(t, isWildcard)
+	require.NoError(t, cache.Close())
+
+	entry, ok := resolver.Snapshot().Levels["*.example.com"]
+	require.True(t, ok)
+	require.Equal(t, []string{"1.1.1.1"}, entry.IPs)
+
+	// new run reuses probe results of the previous one
+	probes.Store(0)
+	cache, err = NewDiskCache(dir)
+	require.NoError(t, err)
+	defer func() {
+		_ = cache.Close()
+	}()
+	resolver = newCountingResolver(&probes)
+	resolver.SetCache(cache)
+	isWildcard, _ = resolver.LookupHost("api.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	isWildcard, _ = resolver.LookupHost("api.example.org", []string{"2.2.2.2"})
+	require.False(t, isWildcard)
+	require.Zero(t, probes.Load())
+	require.Equal(t, map[string]struct{}{"1.1.1.1": {}}, resolver.GetAllWildcardIPs())
+}
+
+func TestResolverCacheTTL(t *testing.T) {
+	now := time.Now()
+	cache := NewMemoryCache()
+	require.NoError(t, cache.Set("*.example.com", CacheEntry{IPs: []string{"1.1.1.1"}, UpdatedAt: now.Add(-2 * time.Hour)}))
+	require.NoError(t, cache.Set("*.example.org", CacheEntry{UpdatedAt: now.Add(-2 * time.Hour)}))
+
+	var probes atomic.Int32
+	resolver := newCountingResolver(&probes)
+	resolver.now = func() time.Time { return now }
+	resolver.SetCache(cache)
+	resolver.SetCacheTTL(time.Hour)
+	resolver.SetZoneTTL("example.com", 24*time.Hour)
+
+	// example.com results are still valid
+	isWildcard, _ := resolver.LookupHost("www.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	require.Zero(t, probes.Load())
+
+	// example.org results expired and are probed again
+	_, _ = resolver.LookupHost("www.example.org", []string{"2.2.2.2"})
+	require.Positive(t, probes.Load())
+	entry, ok := cache.Get("*.example.org")
+	require.True(t, ok)
+	require.Equal(t, now, entry.UpdatedAt)
+
+	// in-memory results expire too
+	probes.Store(0)
+	resolver.now = func() time.Time { return now.Add(25 * time.Hour) }
+	isWildcard, _ = resolver.LookupHost("www.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	require.Positive(t, probes.Load())
+}
+
+// countingCache counts the writes to the cache
+type countingCache struct {
+	*MemoryCache
+	sets atomic.Int32
+}
+
+func (c *countingCache) Set(level string, entry CacheEntry) error {
+	c.sets.Add(1)
+	return c.MemoryCache.Set(level, entry)
+}
+
+func TestResolverCachePersistsChangesOnly(t *testing.T) {
+	var probes atomic.Int32
+	cache := &countingCache{MemoryCache: NewMemoryCache()}
+	resolver := newCountingResolver(&probes)
+	resolver.SetCache(cache)
+
+	// non matching hosts re-probe the cached wildcard level
+	for i := 0; i < 10; i++ {
+		_, _ = resolver.LookupHost("www.example.com", []string{"2.2.2.2"})
+	}
+	require.Equal(t, int32(1), cache.sets.Load())
+}
+
+func TestResolverBusyLevelExpires(t *testing.T) {
+	now := time.Now()
+	var answer atomic.Value
+	answer.Store("1.1.1.1")
+	resolver := NewResolver([]string{"example.com"}, func(host string) ([]string, error) {
+		return []string{answer.Load().(string)}, nil
+	})
+	resolver.now = func() time.Time { return now }
+	resolver.SetCacheTTL(time.Hour)
+
+	_, _ = resolver.LookupHost("a.example.com", []string{"3.3.3.3"})
+	answer.Store("2.2.2.2")
+	// re-probes of non matching hosts do not refresh the level
+	for i := 0; i < 3; i++ {
+		now = now.Add(25 * time.Minute)
+		_, _ = resolver.LookupHost("b.example.com", []string{"3.3.3.3"})
+	}
+	require.Equal(t, map[string]struct{}{"2.2.2.2": {}}, resolver.GetAllWildcardIPs())
+}
+
+func TestResolverSharedCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := NewMemoryCache()
+	var probesA, probesB atomic.Int32
+	a, b := newCountingResolver(&probesA), newCountingResolver(&probesB)
+	for _, resolver := range []*Resolver{a, b} {
+		resolver.now = func() time.Time { return now }
+		resolver.SetCache(cache)
+		resolver.SetCacheTTL(time.Hour)
+	}
+
+	_, _ = a.LookupHost("www.example.com", []string{"1.1.1.1"})
+	now = now.Add(2 * time.Hour)
+	// b probes again since the shared entry expired
+	_, _ = b.LookupHost("www.example.com", []string{"1.1.1.1"})
+	require.Positive(t, probesB.Load())
+
+	// a's in-memory result expired but the shared entry written by b is fresh
+	probesA.Store(0)
+	isWildcard, _ := a.LookupHost("www.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	require.Zero(t, probesA.Load())
+	entry, ok := cache.Get("*.example.com")
+	require.True(t, ok)
+	require.Equal(t, now, entry.UpdatedAt)
+}
+
+func TestResolverSnapshot(t *testing.T) {
+	var probes atomic.Int32
+	worker := newCountingResolver(&probes)
+	_, _ = worker.LookupHost("www.example.com", []string{"1.1.1.1"})
+	_, _ = worker.LookupHost("www.example.org", []string{"2.2.2.2"})
+
+	var buf bytes.Buffer
+	_, err := worker.Snapshot().WriteTo(&buf)
+	require.NoError(t, err)
+	snapshot, err := ReadSnapshot(&buf)
+	require.NoError(t, err)
+	require.Len(t, snapshot.Levels, 2)
+	require.Equal(t, worker.GetAllWildcardIPs(), snapshot.WildcardIPs())
+
+	probes.Store(0)
+	other := newCountingResolver(&probes)
+	cache := NewMemoryCache()
+	other.SetCache(cache)
+	other.ImportSnapshot(snapshot)
+	isWildcard, _ := other.LookupHost("api.example.com", []string{"1.1.1.1"})
+	require.True(t, isWildcard)
+	isWildcard, _ = other.LookupHost("api.example.org", []string{"2.2.2.2"})
+	require.False(t, isWildcard)
+	require.Zero(t, probes.Load())
+
+	entry, ok := cache.Get("*.example.com")
+	require.True(t, ok)
+	require.True(t, entry.IsWildcard())
+}
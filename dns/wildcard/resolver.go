@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	mapsutil "github.com/projectdiscovery/utils/maps"
 	sliceutil "github.com/projectdiscovery/utils/slice"
@@ -27,16 +28,18 @@ const (
 // Resolver represents a wildcard resolver extracted from shuffledns.
 type Resolver struct {
 	Domains *sliceutil.SyncSlice[string]
-	lookup  LookupFunc
+	lookup  RecordLookupFunc
 
-	levelAnswersNormalCache *mapsutil.SyncLockMap[string, struct{}]
+	levelAnswersNormalCache *mapsutil.SyncLockMap[string, time.Time]
 	wildcardAnswersCache    *mapsutil.SyncLockMap[string, wildcardAnswerCacheValue]
 
 	probeCount int
-}
 
-type wildcardAnswerCacheValue struct {
-	IPS *mapsutil.SyncLockMap[string, struct{}]
+	// optional persistent cache of probe results
+	cache    Cache
+	cacheTTL time.Duration
+	zoneTTLs *mapsutil.SyncLockMap[string, time.Duration]
+	now      func() time.Time
 }
 
 func mapValues(m *mapsutil.SyncLockMap[string, struct{}]) map[string]struct{} {
@@ -62,6 +65,23 @@ func NewResolver(domains []string, lookup LookupFunc) *Resolver {
 
 // NewResolverWithDomains initializes a resolver with a pre-built domain slice.
 func NewResolverWithDomains(domains *sliceutil.SyncSlice[string], lookup LookupFunc) *Resolver {
+	return NewRecordResolverWithDomains(domains, func(host string) (Records, error) {
+		ips, err := lookup(host)
+		return Records{A: ips}, err
+	})
+}
+
+// NewRecordResolver initializes a resolver using typed records to find wildcards.
+// Besides ip overlap, hosts are treated as wildcard when their cname terminal or
+// records of other types (ex: TXT, MX) match the wildcard probes.
+func NewRecordResolver(domains []string, lookup RecordLookupFunc) *Resolver {
+	fqdns := sliceutil.NewSyncSlice[string]()
+	fqdns.Append(domains...)
+	return NewRecordResolverWithDomains(fqdns, lookup)
+}
+
+// NewRecordResolverWithDomains initializes a record resolver with a pre-built domain slice.
+func NewRecordResolverWithDomains(domains *sliceutil.SyncSlice[string], lookup RecordLookupFunc) *Resolver {
 	if domains == nil {
 		domains = sliceutil.NewSyncSlice[string]()
 	}
@@ -69,9 +89,11 @@ func NewResolverWithDomains(domains *sliceutil.SyncSlice[string], lookup LookupF
 	return &Resolver{
 		Domains:                 domains,
 		lookup:                  lookup,
-		levelAnswersNormalCache: mapsutil.NewSyncLockMap[string, struct{}](),
+		levelAnswersNormalCache: mapsutil.NewSyncLockMap[string, time.Time](),
 		wildcardAnswersCache:    mapsutil.NewSyncLockMap[string, wildcardAnswerCacheValue](),
 		probeCount:              DefaultWildcardProbeCount,
+		zoneTTLs:                mapsutil.NewSyncLockMap[string, time.Duration](),
+		now:                     time.Now,
 	}
 }
 
@@ -83,43 +105,37 @@ func (w *Resolver) SetProbeCount(count int) {
 	}
 }
 
-// probeWildcardIPs probes the given wildcard pattern multiple times concurrently and returns all IPs found.
+// probeWildcard probes the given wildcard pattern multiple times concurrently and returns all answers found.
 // If the first probe returns no answers, the level is treated as a normal level.
 // Transport or resolver errors are returned separately so callers do not cache them as normal answers.
 // First query is executed sequentially for early exit, remaining queries run in parallel.
-func (w *Resolver) probeWildcardIPs(pattern string, count int) ([]string, probeState) {
+func (w *Resolver) probeWildcard(pattern string, count int) ([]Records, probeState) {
 	if count <= 0 {
 		return nil, probeStateNoAnswers
 	}
 
-	ips := sliceutil.NewSyncSlice[string]()
+	var (
+		mu      sync.Mutex
+		results []Records
+	)
 
-	probe := func() ([]string, probeState) {
+	probe := func() (Records, probeState) {
 		probeHost := strings.ReplaceAll(pattern, "*.", xid.New().String()+".")
 		answers, err := w.lookup(probeHost)
 		if err != nil {
-			return nil, probeStateError
+			return Records{}, probeStateError
 		}
-		if len(answers) == 0 {
-			return nil, probeStateNoAnswers
+		if answers.IsEmpty() {
+			return Records{}, probeStateNoAnswers
 		}
 		return answers, probeStateResolved
 	}
 
-	resultIPs, state := probe()
+	result, state := probe()
 	if state != probeStateResolved {
 		return nil, state
 	}
-	if len(resultIPs) > 0 {
-		ips.Append(resultIPs...)
-	}
-
-	if count == 1 {
-		if ips.Len() == 0 {
-			return nil, probeStateNoAnswers
-		}
-		return sliceutil.Dedupe(ips.Slice), probeStateResolved
-	}
+	results = append(results, result)
 
 	var wg sync.WaitGroup
 	for i := 1; i < count; i++ {
@@ -127,20 +143,18 @@ func (w *Resolver) probeWildcardIPs(pattern string, count int) ([]string, probeS
 		go func() {
 			defer wg.Done()
 
-			resultIPs, state := probe()
-			if state == probeStateResolved && len(resultIPs) > 0 {
-				ips.Append(resultIPs...)
+			result, state := probe()
+			if state == probeStateResolved {
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
 			}
 		}()
 	}
 
 	wg.Wait()
 
-	if ips.Len() == 0 {
-		return nil, probeStateNoAnswers
-	}
-
-	return sliceutil.Dedupe(ips.Slice), probeStateResolved
+	return results, probeStateResolved
 }
 
 // generateWildcardPermutations generates wildcard permutations for a given subdomain
@@ -179,6 +193,13 @@ func generateWildcardPermutations(subdomain, domain string) []string {
 // If any of the host IPs overlap with wildcard answers collected for those
 // levels, the host is treated as wildcard-backed.
 func (w *Resolver) LookupHost(host string, knownIPs []string) (bool, map[string]struct{}) {
+	return w.LookupHostRecords(host, Records{A: knownIPs})
+}
+
+// LookupHostRecords is like LookupHost but matches the typed records of the
+// host, i.e. the host is also treated as wildcard-backed if its cname terminal
+// or any record of other types matches the wildcard answers.
+func (w *Resolver) LookupHostRecords(host string, known Records) (bool, map[string]struct{}) {
 	wildcards := make(map[string]struct{})
 
 	var domain string
@@ -209,6 +230,9 @@ func (w *Resolver) LookupHost(host string, knownIPs []string) (bool, map[string]
 
 		original := h
 
+		// Load probe results of previous runs (if any) and drop expired ones
+		w.loadLevel(original)
+
 		// Check if we have already resolved this host level successfully
 		// and if so, use the cached answer
 		//
@@ -216,21 +240,29 @@ func (w *Resolver) LookupHost(host string, knownIPs []string) (bool, map[string]
 		// and it is used always for resolutions in future.
 		cachedValue, cachedValueOk := w.wildcardAnswersCache.Get(original)
 		if cachedValueOk {
-			for _, knownIP := range knownIPs {
-				if _, ipExists := cachedValue.IPS.Get(knownIP); ipExists {
-					return true, mapValues(cachedValue.IPS)
-				}
+			if cachedValue.matches(known) {
+				return true, mapValues(cachedValue.IPS)
 			}
-			if extraIPs, state := w.probeWildcardIPs(original, reProbeCount); state == probeStateResolved && len(extraIPs) > 0 {
-				for _, record := range extraIPs {
-					wildcards[record] = struct{}{}
-					_ = cachedValue.IPS.Set(record, struct{}{})
+			if extra, state := w.probeWildcard(original, reProbeCount); state == probeStateResolved {
+				changed := false
+				for _, records := range extra {
+					if cachedValue.add(records) {
+						changed = true
+					}
+					for _, ip := range records.IPs() {
+						wildcards[ip] = struct{}{}
+					}
 				}
+				// UpdatedAt is the time of the first probe so that answers
+				// the wildcard no longer serves expire with the level
 				_ = w.wildcardAnswersCache.Set(original, cachedValue)
-				for _, knownIP := range knownIPs {
-					if _, ipExists := cachedValue.IPS.Get(knownIP); ipExists {
-						return true, mapValues(cachedValue.IPS)
-					}
+				// re-probes happen for every non matching host of the level,
+				// the persistent cache is only updated with new answers
+				if changed {
+					w.persistLevel(original, cachedValue)
+				}
+				if cachedValue.matches(known) {
+					return true, mapValues(cachedValue.IPS)
 				}
 			}
 		}
@@ -242,44 +274,47 @@ func (w *Resolver) LookupHost(host string, knownIPs []string) (bool, map[string]
 			continue
 		}
 
-		probeIPs, state := w.probeWildcardIPs(original, w.probeCount)
+		probes, state := w.probeWildcard(original, w.probeCount)
 		if state == probeStateNoAnswers {
-			_ = w.levelAnswersNormalCache.Set(original, struct{}{})
+			now := w.now()
+			_ = w.levelAnswersNormalCache.Set(original, now)
+			w.persistLevel(original, wildcardAnswerCacheValue{UpdatedAt: now})
 			continue
 		}
 		if state != probeStateResolved {
 			continue
 		}
 
-		if len(probeIPs) > 0 {
-			if !cachedValueOk {
-				cachedValue.IPS = mapsutil.NewSyncLockMap[string, struct{}]()
-			}
-			for _, record := range probeIPs {
-				wildcards[record] = struct{}{}
-				_ = cachedValue.IPS.Set(record, struct{}{})
+		changed := !cachedValueOk
+		if !cachedValueOk {
+			cachedValue = newWildcardAnswerCacheValue()
+			cachedValue.UpdatedAt = w.now()
+		}
+		for _, records := range probes {
+			if cachedValue.add(records) {
+				changed = true
 			}
-			_ = w.wildcardAnswersCache.Set(original, cachedValue)
-			for _, knownIP := range knownIPs {
-				if _, ipExists := cachedValue.IPS.Get(knownIP); ipExists {
-					return true, mapValues(cachedValue.IPS)
-				}
+			for _, ip := range records.IPs() {
+				wildcards[ip] = struct{}{}
 			}
+		}
+		_ = w.wildcardAnswersCache.Set(original, cachedValue)
+		if changed {
+			w.persistLevel(original, cachedValue)
+		}
+		if cachedValue.matches(known) {
+			return true, mapValues(cachedValue.IPS)
+		}
 
-			for i := 0; i < w.probeCount; i++ {
-				answers, err := w.lookup(host)
-				if err == nil {
-					for _, record := range answers {
-						if _, ipExists := cachedValue.IPS.Get(record); ipExists {
-							return true, mapValues(cachedValue.IPS)
-						}
-					}
-				}
+		for i := 0; i < w.probeCount; i++ {
+			answers, err := w.lookup(host)
+			if err == nil && cachedValue.matches(answers) {
+				return true, mapValues(cachedValue.IPS)
 			}
 		}
 	}
 
-	for _, knownIP := range knownIPs {
+	for _, knownIP := range known.IPs() {
 		if _, ok := wildcards[knownIP]; ok {
 			return true, wildcards
 		}
@@ -0,0 +1,167 @@
+package wildcard
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// SetCache sets a persistent cache of probe results (ex: NewDiskCache) so
+// that wildcard levels probed by previous runs or other resolvers are not
+// probed again. Probe results older than the ttl (see SetCacheTTL) are ignored.
+func (w *Resolver) SetCache(cache Cache) {
+	w.cache = cache
+}
+
+// SetCacheTTL sets the default time after which probe results are probed again.
+// Zero (default) means probe results never expire.
+func (w *Resolver) SetCacheTTL(ttl time.Duration) {
+	w.cacheTTL = ttl
+}
+
+// SetZoneTTL sets the ttl of probe results of given zone and its subdomains
+// overriding the default ttl (the most specific zone wins)
+func (w *Resolver) SetZoneTTL(zone string, ttl time.Duration) {
+	_ = w.zoneTTLs.Set(strings.ToLower(strings.TrimSuffix(zone, ".")), ttl)
+}
+
+// ttl returns the ttl of probe results of given level (ex: *.a.example.com)
+func (w *Resolver) ttl(level string) time.Duration {
+	zone := strings.TrimPrefix(level, "*.")
+	for {
+		if ttl, ok := w.zoneTTLs.Get(zone); ok {
+			return ttl
+		}
+		idx := strings.IndexByte(zone, '.')
+		if idx < 0 {
+			return w.cacheTTL
+		}
+		zone = zone[idx+1:]
+	}
+}
+
+// expired returns true if the probe result of given level is older than its ttl
+func (w *Resolver) expired(level string, updatedAt time.Time) bool {
+	ttl := w.ttl(level)
+	return ttl > 0 && w.now().Sub(updatedAt) > ttl
+}
+
+// loadLevel drops expired in-memory results of given level and loads the
+// result of the persistent cache if the level was not probed yet. The
+// persistent cache may be shared and is only cleaned if its own entry expired.
+func (w *Resolver) loadLevel(level string) {
+	if value, ok := w.wildcardAnswersCache.Get(level); ok && w.expired(level, value.UpdatedAt) {
+		w.wildcardAnswersCache.Delete(level)
+	}
+	if updatedAt, ok := w.levelAnswersNormalCache.Get(level); ok && w.expired(level, updatedAt) {
+		w.levelAnswersNormalCache.Delete(level)
+	}
+	if w.cache == nil || w.wildcardAnswersCache.Has(level) || w.levelAnswersNormalCache.Has(level) {
+		return
+	}
+	entry, ok := w.cache.Get(level)
+	if !ok {
+		return
+	}
+	if w.expired(level, entry.UpdatedAt) {
+		w.deleteLevel(level)
+		return
+	}
+	w.mergeLevel(level, entry)
+}
+
+// mergeLevel merges the entry into the in-memory caches. Wildcard answers
+// take precedence over normal (non wildcard) results.
+func (w *Resolver) mergeLevel(level string, entry CacheEntry) {
+	if !entry.IsWildcard() {
+		if !w.wildcardAnswersCache.Has(level) {
+			_ = w.levelAnswersNormalCache.Set(level, entry.UpdatedAt)
+		}
+		return
+	}
+	value, ok := w.wildcardAnswersCache.Get(level)
+	if !ok {
+		value = newWildcardAnswerCacheValue()
+	}
+	for _, ip := range entry.IPs {
+		_ = value.IPS.Set(ip, struct{}{})
+	}
+	for _, cname := range entry.CNAMEs {
+		_ = value.CNAMEs.Set(cname, struct{}{})
+	}
+	for _, record := range entry.Records {
+		_ = value.Records.Set(record, struct{}{})
+	}
+	if entry.UpdatedAt.After(value.UpdatedAt) {
+		value.UpdatedAt = entry.UpdatedAt
+	}
+	_ = w.wildcardAnswersCache.Set(level, value)
+	w.levelAnswersNormalCache.Delete(level)
+}
+
+// persistLevel stores the probe result of given level in the persistent cache
+func (w *Resolver) persistLevel(level string, value wildcardAnswerCacheValue) {
+	if w.cache == nil {
+		return
+	}
+	_ = w.cache.Set(level, value.entry())
+}
+
+func (w *Resolver) deleteLevel(level string) {
+	if w.cache != nil {
+		_ = w.cache.Delete(level)
+	}
+}
+
+// Snapshot returns the (non expired) probe results of the resolver
+func (w *Resolver) Snapshot() *Snapshot {
+	snapshot := &Snapshot{CreatedAt: w.now(), Levels: map[string]CacheEntry{}}
+	_ = w.levelAnswersNormalCache.Iterate(func(level string, updatedAt time.Time) error {
+		if !w.expired(level, updatedAt) {
+			snapshot.Levels[level] = CacheEntry{UpdatedAt: updatedAt}
+		}
+		return nil
+	})
+	_ = w.wildcardAnswersCache.Iterate(func(level string, value wildcardAnswerCacheValue) error {
+		if !w.expired(level, value.UpdatedAt) {
+			snapshot.Levels[level] = value.entry()
+		}
+		return nil
+	})
+	return snapshot
+}
+
+// ImportSnapshot merges the (non expired) probe results of a snapshot
+// (ex: exported by another worker) into the resolver and its persistent cache
+func (w *Resolver) ImportSnapshot(snapshot *Snapshot) {
+	for level, entry := range snapshot.Levels {
+		if w.expired(level, entry.UpdatedAt) {
+			continue
+		}
+		w.mergeLevel(level, entry)
+		if value, ok := w.wildcardAnswersCache.Get(level); ok {
+			w.persistLevel(level, value)
+		} else if updatedAt, ok := w.levelAnswersNormalCache.Get(level); ok {
+			w.persistLevel(level, wildcardAnswerCacheValue{UpdatedAt: updatedAt})
+		}
+	}
+}
+
+// entry returns the cache entry of the value
+func (v wildcardAnswerCacheValue) entry() CacheEntry {
+	return CacheEntry{
+		IPs:       sortedKeys(mapValues(v.IPS)),
+		CNAMEs:    sortedKeys(mapValues(v.CNAMEs)),
+		Records:   sortedKeys(mapValues(v.Records)),
+		UpdatedAt: v.UpdatedAt,
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
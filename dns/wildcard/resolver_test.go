@@ -1,6 +1,7 @@
 package wildcard
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -8,6 +9,7 @@ import (
 	"sync/atomic"
 	"testing"
 
+	"github.com/projectdiscovery/utils/dns/dnstest"
 	sliceutil "github.com/projectdiscovery/utils/slice"
 	"github.com/stretchr/testify/require"
 )
@@ -173,3 +175,73 @@ func TestNewResolverWithDomainsSharesDomainSlice(t *testing.T) {
 	isWildcard, _ := resolver.LookupHost("www.example.org", []string{"1.1.1.1"})
 	require.True(t, isWildcard)
 }
+
+func TestRecordResolverCNAMETerminal(t *testing.T) {
+	var counter atomic.Int32
+	resolver := NewRecordResolver([]string{"example.com"}, func(host string) (Records, error) {
+		// rotating cdn ips, only the cname terminal is stable
+		ip := fmt.Sprintf("10.0.0.%d", counter.Add(1))
+		switch host {
+		case "dedicated.example.com":
+			return Records{A: []string{"192.168.1.1"}, CNAME: []string{"dedicated.other-cdn.net"}}, nil
+		default:
+			return Records{A: []string{ip}, CNAME: []string{"alias.example.com.", "Edge.CDN.net."}}, nil
+		}
+	})
+
+	isWildcard, _ := resolver.LookupHostRecords("www.example.com", Records{A: []string{"172.16.0.1"}, CNAME: []string{"edge.cdn.net"}})
+	require.True(t, isWildcard)
+
+	isWildcard, _ = resolver.LookupHostRecords("dedicated.example.com", Records{A: []string{"192.168.1.1"}, CNAME: []string{"dedicated.other-cdn.net"}})
+	require.False(t, isWildcard)
+}
+
+func TestRecordResolverOtherRecords(t *testing.T) {
+	resolver := NewRecordResolver([]string{"example.com"}, func(host string) (Records, error) {
+		if host == "mail.example.com" {
+			return Records{Other: map[string][]string{"MX": {"10 mx.example.com"}}}, nil
+		}
+		// TXT only wildcard
+		return Records{Other: map[string][]string{"txt": {"v=spf1 -all"}}}, nil
+	})
+
+	isWildcard, wildcards := resolver.LookupHostRecords("random.example.com", Records{Other: map[string][]string{"TXT": {"v=spf1 -all"}}})
+	require.True(t, isWildcard)
+	require.Empty(t, wildcards)
+
+	isWildcard, _ = resolver.LookupHostRecords("mail.example.com", Records{Other: map[string][]string{"MX": {"10 mx.example.com"}}})
+	require.False(t, isWildcard)
+
+	entry := resolver.Snapshot().Levels["*.example.com"]
+	require.Equal(t, []string{"TXT v=spf1 -all"}, entry.Records)
+}
+
+func TestResolverLocalServer(t *testing.T) {
+	srv, err := dnstest.NewServer(&dnstest.Zone{
+		Origin: "example.com",
+		Records: []dnstest.Record{
+			{Name: "www", Type: "A", Value: "192.0.2.1"},
+			{Name: "*.wild", Type: "A", Value: "192.0.2.2"},
+		},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = srv.Close()
+	}()
+
+	netResolver := srv.Resolver()
+	resolver := NewResolver([]string{"example.com"}, func(host string) ([]string, error) {
+		ips, err := netResolver.LookupHost(context.Background(), host)
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, nil
+		}
+		return ips, err
+	})
+
+	isWildcard, _ := resolver.LookupHost("api.wild.example.com", []string{"192.0.2.2"})
+	require.True(t, isWildcard)
+	isWildcard, _ = resolver.LookupHost("www.example.com", []string{"192.0.2.1"})
+	require.False(t, isWildcard)
+	require.Equal(t, map[string]struct{}{"192.0.2.2": {}}, resolver.GetAllWildcardIPs())
+}
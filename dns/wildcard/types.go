@@ -1,4 +1,120 @@
 package wildcard
 
+import (
+	"strings"
+	"time"
+
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
 // LookupFunc resolves a host and returns the address answers used for wildcard matching.
 type LookupFunc func(host string) ([]string, error)
+
+// RecordLookupFunc resolves a host and returns its typed records used for wildcard matching.
+type RecordLookupFunc func(host string) (Records, error)
+
+// Records are the typed answers of a host
+type Records struct {
+	A    []string
+	AAAA []string
+	// CNAME is the cname chain in resolution order, the last one is the terminal target
+	CNAME []string
+	// Other contains values of other record types keyed by type (ex: TXT, MX)
+	Other map[string][]string
+}
+
+// IPs returns the A and AAAA answers
+func (r Records) IPs() []string {
+	return append(append([]string{}, r.A...), r.AAAA...)
+}
+
+// CNAMETerminal returns the last target of the cname chain (empty if none)
+func (r Records) CNAMETerminal() string {
+	if len(r.CNAME) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(r.CNAME[len(r.CNAME)-1], "."))
+}
+
+// IsEmpty returns true if there are no records
+func (r Records) IsEmpty() bool {
+	if len(r.A) > 0 || len(r.AAAA) > 0 || len(r.CNAME) > 0 {
+		return false
+	}
+	for _, values := range r.Other {
+		if len(values) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// otherRecords returns the other records as "TYPE value" entries
+func (r Records) otherRecords() []string {
+	var records []string
+	for recordType, values := range r.Other {
+		for _, value := range values {
+			records = append(records, strings.ToUpper(recordType)+" "+value)
+		}
+	}
+	return records
+}
+
+// wildcardAnswerCacheValue contains the wildcard answers of a level
+type wildcardAnswerCacheValue struct {
+	IPS *mapsutil.SyncLockMap[string, struct{}]
+	// CNAMEs are the cname terminals of the wildcard probes
+	CNAMEs *mapsutil.SyncLockMap[string, struct{}]
+	// Records are the other records of the wildcard probes ("TYPE value")
+	Records   *mapsutil.SyncLockMap[string, struct{}]
+	UpdatedAt time.Time
+}
+
+func newWildcardAnswerCacheValue() wildcardAnswerCacheValue {
+	return wildcardAnswerCacheValue{
+		IPS:     mapsutil.NewSyncLockMap[string, struct{}](),
+		CNAMEs:  mapsutil.NewSyncLockMap[string, struct{}](),
+		Records: mapsutil.NewSyncLockMap[string, struct{}](),
+	}
+}
+
+// add adds the probe answers to the value and returns true if any of
+// them was not known yet
+func (v wildcardAnswerCacheValue) add(records Records) bool {
+	changed := false
+	set := func(m *mapsutil.SyncLockMap[string, struct{}], value string) {
+		if !m.Has(value) {
+			_ = m.Set(value, struct{}{})
+			changed = true
+		}
+	}
+	for _, ip := range records.IPs() {
+		set(v.IPS, ip)
+	}
+	if terminal := records.CNAMETerminal(); terminal != "" {
+		set(v.CNAMEs, terminal)
+	}
+	for _, record := range records.otherRecords() {
+		set(v.Records, record)
+	}
+	return changed
+}
+
+// matches returns true if the records of a host overlap with the wildcard answers
+// i.e. same ip, same cname terminal or same record of another type
+func (v wildcardAnswerCacheValue) matches(records Records) bool {
+	for _, ip := range records.IPs() {
+		if v.IPS.Has(ip) {
+			return true
+		}
+	}
+	if terminal := records.CNAMETerminal(); terminal != "" && v.CNAMEs.Has(terminal) {
+		return true
+	}
+	for _, record := range records.otherRecords() {
+		if v.Records.Has(record) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,399 @@
+package errkit
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrKindCircuitOpen indicates that the operation was short-circuited
+// because too many network errors were observed for the target
+var ErrKindCircuitOpen = NewPrimitiveErrKind("circuit-open-error", "circuit breaker open", nil)
+
+// BreakerState is the state of a circuit
+type BreakerState int
+
+const (
+	// StateClosed allows all operations
+	StateClosed BreakerState = iota
+	// StateOpen short-circuits all operations until cooldown expires
+	StateOpen
+	// StateHalfOpen allows a limited number of probe operations
+	StateHalfOpen
+)
+
+// String returns the string representation of the state
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// BreakerEntry is the state of the circuit for a single key
+// it only contains plain values so that it can be stored in a shared store
+type BreakerEntry struct {
+	State     BreakerState `json:"state"`
+	Permanent []time.Time  `json:"permanent,omitempty"`
+	Temporary []time.Time  `json:"temporary,omitempty"`
+	OpenedAt  time.Time    `json:"opened_at,omitempty"`
+	Probes    int          `json:"probes,omitempty"`
+	LastError string       `json:"last_error,omitempty"`
+	LastKind  string       `json:"last_kind,omitempty"`
+}
+
+// BreakerStore stores circuit entries by key
+type BreakerStore interface {
+	// Get returns the entry for given key
+	Get(key string) (BreakerEntry, bool)
+	// Set stores the entry for given key
+	Set(key string, entry BreakerEntry)
+	// Delete removes the entry for given key
+	Delete(key string)
+}
+
+// BreakerStoreEvicter is implemented by stores which support eviction,
+// the CircuitBreaker periodically evicts idle closed entries from them
+type BreakerStoreEvicter interface {
+	// Evict removes all entries for which fn returns true
+	Evict(fn func(key string, entry BreakerEntry) bool)
+}
+
+// breakerSweepInterval is the minimum interval between evictions of idle entries
+const breakerSweepInterval = time.Minute
+
+// MemoryBreakerStore is an in-memory BreakerStore
+type MemoryBreakerStore struct {
+	mu      sync.RWMutex
+	entries map[string]BreakerEntry
+}
+
+// NewMemoryBreakerStore creates a new in-memory breaker store
+func NewMemoryBreakerStore() *MemoryBreakerStore {
+	return &MemoryBreakerStore{entries: map[string]BreakerEntry{}}
+}
+
+// Get implements BreakerStore
+func (m *MemoryBreakerStore) Get(key string) (BreakerEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[key]
+	return entry, ok
+}
+
+// Set implements BreakerStore
+func (m *MemoryBreakerStore) Set(key string, entry BreakerEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = entry
+}
+
+// Delete implements BreakerStore
+func (m *MemoryBreakerStore) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// Evict implements BreakerStoreEvicter
+func (m *MemoryBreakerStore) Evict(fn func(key string, entry BreakerEntry) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, entry := range m.entries {
+		if fn(key, entry) {
+			delete(m.entries, key)
+		}
+	}
+}
+
+// BreakerOption is a configuration option for the CircuitBreaker
+type BreakerOption func(*CircuitBreaker)
+
+// WithBreakerWindow sets the sliding window in which errors are counted (default 1m)
+func WithBreakerWindow(window time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.window = window
+	}
+}
+
+// WithPermanentThreshold sets the number of permanent network errors
+// within the window after which the circuit opens (default 3)
+func WithPermanentThreshold(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.permanentThreshold = n
+	}
+}
+
+// WithTemporaryThreshold sets the number of temporary network errors
+// within the window after which the circuit opens (default 10)
+func WithTemporaryThreshold(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.temporaryThreshold = n
+	}
+}
+
+// WithCooldown sets the duration the circuit stays open before probing (default 30s)
+func WithCooldown(cooldown time.Duration) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.cooldown = cooldown
+	}
+}
+
+// WithHalfOpenProbes sets the number of concurrent probes allowed in half-open state (default 1)
+func WithHalfOpenProbes(n int) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.probes = n
+	}
+}
+
+// WithBreakerStore sets the store used for circuit entries (default in-memory)
+func WithBreakerStore(store BreakerStore) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.store = store
+	}
+}
+
+// WithBreakerKinds adds extra error kinds used while classifying errors
+func WithBreakerKinds(kinds ...ErrKind) BreakerOption {
+	return func(b *CircuitBreaker) {
+		b.kinds = append(b.kinds, kinds...)
+	}
+}
+
+// CircuitBreaker tracks network errors per key (ex: host or host:port) and
+// short-circuits further operations once a key is considered dead
+//
+//	breaker := errkit.NewCircuitBreaker()
+//	err := breaker.Do(host, func() error {
+//		return scan(host)
+//	})
+type CircuitBreaker struct {
+	mu                 sync.Mutex
+	store              BreakerStore
+	window             time.Duration
+	cooldown           time.Duration
+	permanentThreshold int
+	temporaryThreshold int
+	probes             int
+	kinds              []ErrKind
+	lastSweep          time.Time
+	now                func() time.Time
+}
+
+// NewCircuitBreaker creates a new circuit breaker with given options
+func NewCircuitBreaker(opts ...BreakerOption) *CircuitBreaker {
+	b := &CircuitBreaker{
+		window:             time.Minute,
+		cooldown:           30 * time.Second,
+		permanentThreshold: 3,
+		temporaryThreshold: 10,
+		probes:             1,
+		now:                time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.store == nil {
+		b.store = NewMemoryBreakerStore()
+	}
+	return b
+}
+
+// Allow checks if an operation for given key is allowed
+// it returns an ErrorX of kind ErrKindCircuitOpen if the circuit is open
+// callers must report the result of allowed operations using Record
+func (b *CircuitBreaker) Allow(key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.store.Get(key)
+	if !ok {
+		return nil
+	}
+	now := b.now()
+	switch entry.State {
+	case StateOpen:
+		if now.Sub(entry.OpenedAt) < b.cooldown {
+			return b.openError(key, entry, now)
+		}
+		// cooldown expired, start probing
+		entry.State = StateHalfOpen
+		entry.Probes = 1
+		b.store.Set(key, entry)
+		return nil
+	case StateHalfOpen:
+		if entry.Probes >= b.probes {
+			return b.openError(key, entry, now)
+		}
+		entry.Probes++
+		b.store.Set(key, entry)
+		return nil
+	}
+	return nil
+}
+
+// Record records the result of an operation for given key
+// a nil error closes a half-open circuit, permanent and temporary network
+// errors are counted and may open the circuit, other errors are ignored
+// except that they close a half-open circuit since the target responded.
+// Deadline errors of half-open circuits are probe failures.
+func (b *CircuitBreaker) Record(key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.store.Get(key)
+	if err == nil {
+		if ok && entry.State != StateClosed {
+			b.store.Delete(key)
+		}
+		return
+	}
+	if IsKind(err, ErrKindCircuitOpen) {
+		return
+	}
+
+	now := b.now()
+	b.sweep(now)
+	kind := GetErrorKind(err, b.kinds...)
+	isPermanent := ErrKindNetworkPermanent.Is(kind) || ErrKindNetworkPermanent.IsParent(kind)
+	isTemporary := ErrKindNetworkTemporary.Is(kind) || ErrKindNetworkTemporary.IsParent(kind)
+	if ok && entry.State == StateHalfOpen && (ErrKindDeadline.Is(kind) || ErrKindDeadline.IsParent(kind)) {
+		// probes of dead targets time out, the probe failed
+		isTemporary = true
+	}
+	if !isPermanent && !isTemporary {
+		if ok && entry.State == StateHalfOpen {
+			// probe reached the target, close the circuit
+			b.store.Delete(key)
+		}
+		return
+	}
+
+	entry.Permanent = pruneWindow(entry.Permanent, now, b.window, b.permanentThreshold)
+	entry.Temporary = pruneWindow(entry.Temporary, now, b.window, b.temporaryThreshold)
+	if isPermanent {
+		entry.Permanent = appendCapped(entry.Permanent, now, b.permanentThreshold)
+	} else {
+		entry.Temporary = appendCapped(entry.Temporary, now, b.temporaryThreshold)
+	}
+	entry.LastKind = kind.String()
+	if cause := Cause(err); cause != nil {
+		entry.LastError = cause.Error()
+	}
+
+	switch {
+	case entry.State == StateHalfOpen:
+		// probe failed, open the circuit again
+		entry.State = StateOpen
+		entry.OpenedAt = now
+		entry.Probes = 0
+	case entry.State == StateClosed:
+		if (b.permanentThreshold > 0 && len(entry.Permanent) >= b.permanentThreshold) ||
+			(b.temporaryThreshold > 0 && len(entry.Temporary) >= b.temporaryThreshold) {
+			entry.State = StateOpen
+			entry.OpenedAt = now
+		}
+	}
+	b.store.Set(key, entry)
+}
+
+// Do executes given function if the circuit for key allows it
+// and records its result
+func (b *CircuitBreaker) Do(key string, fn func() error) error {
+	if err := b.Allow(key); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(key, err)
+	return err
+}
+
+// State returns the current state of the circuit for given key
+func (b *CircuitBreaker) State(key string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.store.Get(key)
+	if !ok {
+		return StateClosed
+	}
+	if entry.State == StateOpen && b.now().Sub(entry.OpenedAt) >= b.cooldown {
+		return StateHalfOpen
+	}
+	return entry.State
+}
+
+// Reset closes the circuit for given key and clears its history
+func (b *CircuitBreaker) Reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.store.Delete(key)
+}
+
+// sweep evicts closed entries without errors in the window since they are
+// equivalent to missing ones, it must be called with the lock held
+func (b *CircuitBreaker) sweep(now time.Time) {
+	evicter, ok := b.store.(BreakerStoreEvicter)
+	if !ok || b.window <= 0 || now.Sub(b.lastSweep) < breakerSweepInterval {
+		return
+	}
+	b.lastSweep = now
+	evicter.Evict(func(key string, entry BreakerEntry) bool {
+		if entry.State != StateClosed {
+			return false
+		}
+		for _, times := range [][]time.Time{entry.Permanent, entry.Temporary} {
+			if len(times) > 0 && now.Sub(times[len(times)-1]) <= b.window {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// openError returns the error returned when circuit does not allow operations
+func (b *CircuitBreaker) openError(key string, entry BreakerEntry, now time.Time) error {
+	x := New("circuit breaker is open",
+		"key", key,
+		"state", entry.State.String(),
+		"permanent_errors", len(entry.Permanent),
+		"temporary_errors", len(entry.Temporary),
+	)
+	if entry.State == StateOpen {
+		x.record.Add("retry_after", b.cooldown-now.Sub(entry.OpenedAt))
+	}
+	if entry.LastError != "" {
+		x.record.Add("last_error", entry.LastError, "last_kind", entry.LastKind)
+	}
+	return x.SetKind(ErrKindCircuitOpen)
+}
+
+// pruneWindow removes timestamps older than given window and keeps
+// at most limit (the threshold) latest timestamps
+func pruneWindow(times []time.Time, now time.Time, window time.Duration, limit int) []time.Time {
+	i := 0
+	if window > 0 {
+		for i < len(times) && now.Sub(times[i]) > window {
+			i++
+		}
+	}
+	if limit > 0 && len(times)-i > limit {
+		i = len(times) - limit
+	}
+	return append([]time.Time{}, times[i:]...)
+}
+
+// appendCapped appends given timestamp while keeping at most limit timestamps
+// nothing is recorded when the threshold is disabled (limit <= 0)
+func appendCapped(times []time.Time, t time.Time, limit int) []time.Time {
+	if limit <= 0 {
+		return times
+	}
+	times = append(times, t)
+	if len(times) > limit {
+		times = times[len(times)-limit:]
+	}
+	return times
+}
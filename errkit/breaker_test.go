@@ -0,0 +1,167 @@
+package errkit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	newBreaker := func() *CircuitBreaker {
+		b := NewCircuitBreaker(
+			WithPermanentThreshold(2),
+			WithTemporaryThreshold(3),
+			WithBreakerWindow(time.Minute),
+			WithCooldown(10*time.Second),
+		)
+		b.now = clock.Now
+		return b
+	}
+	permanentErr := New("dial tcp 10.0.0.1:80: connect: connection refused")
+	temporaryErr := New("i/o timeout").SetKind(ErrKindNetworkTemporary)
+
+	t.Run("Trips On Permanent Errors", func(t *testing.T) {
+		b := newBreaker()
+		key := "10.0.0.1:80"
+		calls := 0
+		for i := 0; i < 5; i++ {
+			_ = b.Do(key, func() error {
+				calls++
+				return permanentErr
+			})
+		}
+		require.Equal(t, 2, calls, "circuit should open after threshold")
+		require.Equal(t, StateOpen, b.State(key))
+
+		err := b.Allow(key)
+		require.Error(t, err)
+		require.True(t, IsKind(err, ErrKindCircuitOpen))
+		require.Equal(t, key, GetAttrValue(err, "key").String())
+		require.Equal(t, int64(2), GetAttrValue(err, "permanent_errors").Int64())
+		require.Equal(t, ErrKindNetworkPermanent.String(), GetAttrValue(err, "last_kind").String())
+	})
+
+	t.Run("Sliding Window", func(t *testing.T) {
+		b := newBreaker()
+		key := "example.com"
+		b.Record(key, temporaryErr)
+		b.Record(key, temporaryErr)
+		clock.Advance(2 * time.Minute)
+		b.Record(key, temporaryErr)
+		require.Equal(t, StateClosed, b.State(key), "old errors should expire")
+		b.Record(key, temporaryErr)
+		b.Record(key, temporaryErr)
+		require.Equal(t, StateOpen, b.State(key))
+	})
+
+	t.Run("Half Open Probing", func(t *testing.T) {
+		b := newBreaker()
+		key := "probe.example.com"
+		b.Record(key, permanentErr)
+		b.Record(key, permanentErr)
+		require.Error(t, b.Allow(key))
+
+		clock.Advance(11 * time.Second)
+		require.Equal(t, StateHalfOpen, b.State(key))
+		require.NoError(t, b.Allow(key), "first probe should be allowed")
+		require.Error(t, b.Allow(key), "only one concurrent probe allowed")
+
+		// failed probe opens the circuit again
+		b.Record(key, permanentErr)
+		require.Equal(t, StateOpen, b.State(key))
+
+		clock.Advance(11 * time.Second)
+		require.NoError(t, b.Allow(key))
+		b.Record(key, nil)
+		require.Equal(t, StateClosed, b.State(key))
+		require.NoError(t, b.Allow(key))
+	})
+
+	t.Run("Ignores Non Network Errors", func(t *testing.T) {
+		b := newBreaker()
+		key := "logic.example.com"
+		for i := 0; i < 10; i++ {
+			b.Record(key, New("invalid template"))
+		}
+		require.Equal(t, StateClosed, b.State(key))
+	})
+
+	t.Run("Non Network Error Closes Half Open", func(t *testing.T) {
+		b := newBreaker()
+		key := "app.example.com"
+		b.Record(key, permanentErr)
+		b.Record(key, permanentErr)
+		clock.Advance(11 * time.Second)
+		require.NoError(t, b.Allow(key))
+		b.Record(key, New("invalid template"))
+		require.Equal(t, StateClosed, b.State(key))
+		require.NoError(t, b.Allow(key))
+	})
+
+	t.Run("Probe Deadline Reopens Circuit", func(t *testing.T) {
+		for _, probeErr := range []error{
+			context.DeadlineExceeded,
+			context.Canceled,
+			New("template execution deadline reached").SetKind(ErrKindDeadline),
+		} {
+			b := newBreaker()
+			key := "slow.example.com"
+			b.Record(key, permanentErr)
+			b.Record(key, permanentErr)
+			clock.Advance(11 * time.Second)
+			require.NoError(t, b.Allow(key))
+			b.Record(key, probeErr)
+			require.Equal(t, StateOpen, b.State(key), probeErr.Error())
+			require.Error(t, b.Allow(key))
+		}
+	})
+
+	t.Run("Idle Closed Entries Are Evicted", func(t *testing.T) {
+		b := newBreaker()
+		b.Record("idle.example.com", permanentErr)
+		b.Record("open.example.com", permanentErr)
+		b.Record("open.example.com", permanentErr)
+		clock.Advance(2 * time.Minute)
+		b.Record("active.example.com", temporaryErr)
+
+		_, ok := b.store.Get("idle.example.com")
+		require.False(t, ok, "idle closed entry should be evicted")
+		_, ok = b.store.Get("open.example.com")
+		require.True(t, ok, "open entry should be kept")
+		_, ok = b.store.Get("active.example.com")
+		require.True(t, ok)
+	})
+
+	t.Run("History Is Capped Without Window", func(t *testing.T) {
+		b := newBreaker()
+		b.window = 0
+		key := "nowindow.example.com"
+		for i := 0; i < 10; i++ {
+			b.Record(key, temporaryErr)
+		}
+		entry, ok := b.store.Get(key)
+		require.True(t, ok)
+		require.Len(t, entry.Temporary, 3)
+	})
+}
@@ -0,0 +1,172 @@
+package errkit
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DedupEntry is a group of errors sharing the same fingerprint
+type DedupEntry struct {
+	// Fingerprint is the stable fingerprint of the errors
+	Fingerprint string `json:"fingerprint"`
+	// Kind is the kind of the errors
+	Kind string `json:"kind"`
+	// Message is the normalized message of the first error
+	Message string `json:"message"`
+	// Count is the number of errors collapsed into this entry
+	Count int `json:"count"`
+	// FirstSeen is the time the first error was added
+	FirstSeen time.Time `json:"first_seen"`
+	// LastSeen is the time the last error was added
+	LastSeen time.Time `json:"last_seen"`
+	// Samples contains attributes of the first few errors
+	Samples []map[string]any `json:"samples,omitempty"`
+	// Sample is the first error added to this entry
+	Sample *ErrorX `json:"sample,omitempty"`
+}
+
+// DedupOption is a configuration option for the DedupSink
+type DedupOption func(*DedupSink)
+
+// WithMaxSamples sets the max number of attribute samples kept per entry (default 3)
+func WithMaxSamples(n int) DedupOption {
+	return func(s *DedupSink) {
+		s.maxSamples = n
+	}
+}
+
+// WithMaxEntries sets the max number of unique fingerprints kept by the sink
+// errors with new fingerprints beyond this limit are only counted as dropped
+// (default 0 means unlimited)
+func WithMaxEntries(n int) DedupOption {
+	return func(s *DedupSink) {
+		s.maxEntries = n
+	}
+}
+
+// DedupSink collapses errors with identical fingerprints into a single entry
+// it is safe for concurrent use
+type DedupSink struct {
+	mu         sync.Mutex
+	entries    map[string]*DedupEntry
+	maxSamples int
+	maxEntries int
+	dropped    int
+}
+
+// NewDedupSink creates a new deduplicating error sink
+func NewDedupSink(opts ...DedupOption) *DedupSink {
+	s := &DedupSink{
+		entries:    map[string]*DedupEntry{},
+		maxSamples: 3,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Add adds given error to the sink and returns true if this is the
+// first time its fingerprint was seen
+func (s *DedupSink) Add(err error) bool {
+	if err == nil {
+		return false
+	}
+	x := FromError(err)
+	fingerprint := x.Fingerprint()
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[fingerprint]
+	if !ok {
+		if s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+			s.dropped++
+			return false
+		}
+		entry = &DedupEntry{
+			Fingerprint: fingerprint,
+			Kind:        x.classifiedKind().String(),
+			FirstSeen:   now,
+			Sample:      x,
+		}
+		if cause := x.Cause(); cause != nil {
+			entry.Message = NormalizeErrorMessage(cause.Error())
+		}
+		s.entries[fingerprint] = entry
+	}
+	entry.Count++
+	entry.LastSeen = now
+	if attrs := x.Attrs(); len(attrs) > 0 && len(entry.Samples) < s.maxSamples {
+		entry.Samples = append(entry.Samples, attrsToMap(attrs))
+	}
+	return !ok
+}
+
+// Get returns the entry for given fingerprint
+func (s *DedupSink) Get(fingerprint string) (DedupEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[fingerprint]
+	if !ok {
+		return DedupEntry{}, false
+	}
+	return *entry, true
+}
+
+// Entries returns all entries ordered by count (highest first)
+func (s *DedupSink) Entries() []DedupEntry {
+	s.mu.Lock()
+	entries := make([]DedupEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, *entry)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].FirstSeen.Before(entries[j].FirstSeen)
+	})
+	return entries
+}
+
+// Len returns the number of unique fingerprints in the sink
+func (s *DedupSink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Dropped returns the number of errors dropped due to max entries limit
+func (s *DedupSink) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Reset removes all entries from the sink
+func (s *DedupSink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = map[string]*DedupEntry{}
+	s.dropped = 0
+}
+
+// attrsToMap converts slog attributes to a map suitable for json encoding
+func attrsToMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			m[a.Key] = attrsToMap(v.Group())
+			continue
+		}
+		m[a.Key] = v.Any()
+	}
+	return m
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +27,8 @@ const (
 	MultiLineErrPrefix = "the following errors occurred:"
 	// Space is the identifier used for indentation
 	Space = " "
+	// maxWrappedErrors is the max number of wrapper errors kept for classification
+	maxWrappedErrors = 8
 )
 
 var (
@@ -52,6 +53,13 @@ type ErrorX struct {
 	record *slog.Record
 	source *slog.Source
 	errs   []error
+	// wrapped contains wrapper errors that were unwrapped while parsing
+	// they are only used for typed classification (ex: *url.Error)
+	wrapped []error
+	// pcs are the program counters of captured stack (resolved lazily)
+	pcs []uintptr
+	// frames are resolved stack frames (ex: restored from json)
+	frames Stack
 }
 
 func (e *ErrorX) init(skipStack ...int) {
@@ -62,22 +70,13 @@ func (e *ErrorX) init(skipStack ...int) {
 			e.record.Time = time.Now()
 		}
 		if EnableTrace {
-			// get fn name
-			var pcs [1]uintptr
 			// skip [runtime.Callers, ErrorX.init, parent]
 			skip := 3
 			if len(skipStack) > 0 {
 				skip = skipStack[0]
 			}
-			runtime.Callers(skip, pcs[:])
-			pc := pcs[0]
-			fs := runtime.CallersFrames([]uintptr{pc})
-			f, _ := fs.Next()
-			e.source = &slog.Source{
-				Function: f.Function,
-				File:     f.File,
-				Line:     f.Line,
-			}
+			// +1 for ErrorX.captureStack
+			e.captureStack(skip + 1)
 		}
 	}
 }
@@ -101,6 +100,18 @@ func (e *ErrorX) append(errs ...error) {
 	}
 }
 
+// appendWrapped keeps given wrapper errors for typed classification
+func (e *ErrorX) appendWrapped(errs ...error) {
+	for _, err := range errs {
+		if len(e.wrapped) >= maxWrappedErrors {
+			return
+		}
+		e.wrapped = append(e.wrapped, err)
+	}
+}
+
+// MarshalJSON returns the json representation of the error
+// it can be restored using UnmarshalJSON
 func (e ErrorX) MarshalJSON() ([]byte, error) {
 	tmp := []string{}
 	for _, err := range e.errs {
@@ -114,11 +125,21 @@ func (e ErrorX) MarshalJSON() ([]byte, error) {
 		"errors": tmp,
 	}
 	if e.record != nil && e.record.NumAttrs() > 0 {
-		m["attrs"] = slog.GroupValue(e.Attrs()...)
+		attrs, err := marshalAttrs(e.Attrs())
+		if err != nil {
+			return nil, err
+		}
+		m["attrs"] = attrs
+	}
+	if e.record != nil && !e.record.Time.IsZero() {
+		m["time"] = e.record.Time
 	}
 	if e.source != nil {
 		m["source"] = e.source
 	}
+	if len(e.pcs) > 0 || len(e.frames) > 0 {
+		m["stack"] = e.Stack()
+	}
 	return json.Marshal(m)
 }
 
@@ -140,6 +161,21 @@ func (e *ErrorX) Attrs() []slog.Attr {
 	return values
 }
 
+// ToErrorX returns the error itself
+func (e *ErrorX) ToErrorX() *ErrorX {
+	return e
+}
+
+// OnError executes given callback once with the error string, it mainly
+// exists for compatibility with callbacks of legacy errorutil errors
+// which are fired when the legacy error is converted into ErrorX
+func (e *ErrorX) OnError(fn func(msg string)) *ErrorX {
+	if fn != nil {
+		fn(e.Error())
+	}
+	return e
+}
+
 // Build returns the object as error interface
 func (e *ErrorX) Build() error {
 	return e
@@ -298,6 +334,10 @@ func (e *ErrorX) SetKind(kind ErrKind) *ErrorX {
 	} else {
 		e.kind = CombineErrKinds(e.kind, kind)
 	}
+	if len(e.pcs) == 0 && len(e.frames) == 0 && isTraceEnabledForKind(kind) {
+		// skip [runtime.Callers, ErrorX.captureStack, ErrorX.SetKind]
+		e.captureStack(3)
+	}
 	return e
 }
 
@@ -378,7 +418,17 @@ func parseError(to *ErrorX, err error) {
 		if to.source == nil && v.source != nil {
 			to.source = v.source
 		}
+		if len(to.pcs) == 0 && len(to.frames) == 0 {
+			to.pcs, to.frames = v.pcs, v.frames
+		}
+		to.appendWrapped(v.wrapped...)
 		to.kind = CombineErrKinds(to.kind, v.kind)
+	case ConvertibleError:
+		if x := v.ToErrorX(); x != nil {
+			parseError(to, x)
+		} else {
+			parseErrorString(to, err)
+		}
 	case JoinedError:
 		foundAny := false
 		for _, e := range v.Unwrap() {
@@ -390,49 +440,57 @@ func parseError(to *ErrorX, err error) {
 		}
 	case WrappedError:
 		if v.Unwrap() != nil {
+			to.appendWrapped(err)
 			parseError(to, v.Unwrap())
 		} else {
-			parseError(to, errors.New(err.Error()))
+			// keep original error to preserve its type (ex: *net.DNSError)
+			parseErrorString(to, err)
 		}
 	case CauseError:
 		to.append(v.Cause())
 		remaining := strings.ReplaceAll(err.Error(), v.Cause().Error(), "")
 		parseError(to, errors.New(remaining))
 	default:
-		errString := err.Error()
-		// try assigning to enriched error
-		if strings.Contains(errString, DelimArrow) {
-			// Split the error by arrow delim
-			parts := strings.Split(errString, DelimArrow)
-			for i := len(parts) - 1; i >= 0; i-- {
-				part := strings.TrimSpace(parts[i])
-				parseError(to, errors.New(part))
-			}
-		} else if strings.Contains(errString, DelimArrowSerialized) {
-			// Split the error by arrow delim
-			parts := strings.Split(errString, DelimArrowSerialized)
-			for i := len(parts) - 1; i >= 0; i-- {
-				part := strings.TrimSpace(parts[i])
-				parseError(to, errors.New(part))
-			}
-		} else if strings.Contains(errString, DelimSemiColon) {
-			// Split the error by semi-colon delim
-			parts := strings.Split(errString, DelimSemiColon)
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				parseError(to, errors.New(part))
-			}
-		} else if strings.Contains(errString, MultiLineErrPrefix) {
-			// remove prefix
-			msg := strings.ReplaceAll(errString, MultiLineErrPrefix, "")
-			parts := strings.Split(msg, DelimMultiLine)
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				parseError(to, errors.New(part))
-			}
-		} else {
-			// this cannot be further unwrapped
-			to.append(err)
+		parseErrorString(to, err)
+	}
+}
+
+// parseErrorString parses errors that cannot be unwrapped using
+// known delimiters, if error can not be split it is appended as is
+func parseErrorString(to *ErrorX, err error) {
+	errString := err.Error()
+	// try assigning to enriched error
+	if strings.Contains(errString, DelimArrow) {
+		// Split the error by arrow delim
+		parts := strings.Split(errString, DelimArrow)
+		for i := len(parts) - 1; i >= 0; i-- {
+			part := strings.TrimSpace(parts[i])
+			parseError(to, errors.New(part))
 		}
+	} else if strings.Contains(errString, DelimArrowSerialized) {
+		// Split the error by arrow delim
+		parts := strings.Split(errString, DelimArrowSerialized)
+		for i := len(parts) - 1; i >= 0; i-- {
+			part := strings.TrimSpace(parts[i])
+			parseError(to, errors.New(part))
+		}
+	} else if strings.Contains(errString, DelimSemiColon) {
+		// Split the error by semi-colon delim
+		parts := strings.Split(errString, DelimSemiColon)
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			parseError(to, errors.New(part))
+		}
+	} else if strings.Contains(errString, MultiLineErrPrefix) {
+		// remove prefix
+		msg := strings.ReplaceAll(errString, MultiLineErrPrefix, "")
+		parts := strings.Split(msg, DelimMultiLine)
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			parseError(to, errors.New(part))
+		}
+	} else {
+		// this cannot be further unwrapped
+		to.append(err)
 	}
 }
@@ -165,3 +165,24 @@ func TestWithAttr(t *testing.T) {
 	emptyErr := WithAttr(originalErr)
 	require.Equal(t, originalErr, emptyErr, "expected original error when no attrs provided")
 }
+
+type convertibleErr struct{}
+
+func (convertibleErr) Error() string { return "legacy error" }
+
+func (convertibleErr) ToErrorX() *ErrorX {
+	return New("legacy error", "tag", "legacy").SetKind(ErrKindNetworkPermanent)
+}
+
+func TestConvertibleError(t *testing.T) {
+	x := FromError(errors.Wrap(convertibleErr{}, "wrapped"))
+	require.True(t, x.Kind().Is(ErrKindNetworkPermanent))
+	require.Equal(t, "legacy", GetAttrValue(x, "tag").String())
+
+	var msgs []string
+	x.OnError(func(msg string) { msgs = append(msgs, msg) })
+	require.Equal(t, []string{x.Error()}, msgs)
+	wrapped := Wrap(x, "another message")
+	_ = wrapped.Error()
+	require.Len(t, msgs, 1, "callbacks should not fire again on wrapping or Error()")
+}
@@ -0,0 +1,259 @@
+package errkit
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// StatusCode is the status of a span, it mirrors OpenTelemetry status codes
+type StatusCode int
+
+const (
+	// StatusUnset is the default status
+	StatusUnset StatusCode = iota
+	// StatusError indicates the operation contains an error
+	StatusError
+	// StatusOK indicates the operation completed successfully
+	StatusOK
+)
+
+// String returns the string representation of the status code
+func (s StatusCode) String() string {
+	switch s {
+	case StatusError:
+		return "Error"
+	case StatusOK:
+		return "Ok"
+	default:
+		return "Unset"
+	}
+}
+
+// span event attribute keys, these follow OpenTelemetry semantic conventions
+// where applicable
+const (
+	// SpanEventName is the name of the span event recorded for errors
+	SpanEventName = "exception"
+	// AttrExceptionType is the attribute key for the error kind
+	AttrExceptionType = "exception.type"
+	// AttrExceptionMessage is the attribute key for the error message
+	AttrExceptionMessage = "exception.message"
+	// AttrExceptionStacktrace is the attribute key for the stack of the error
+	AttrExceptionStacktrace = "exception.stacktrace"
+	// AttrErrorCause is the attribute key for the root cause of the error
+	AttrErrorCause = "error.cause"
+	// AttrErrorChain is the attribute key for the wrapped error chain
+	AttrErrorChain = "error.chain"
+	// AttrErrorAttrsPrefix is the prefix of all error attributes
+	AttrErrorAttrsPrefix = "error.attrs."
+)
+
+// Span is the subset of a tracing span used to export errors
+// it is intentionally small so that it can be backed by OpenTelemetry
+// (trace.Span) without errkit depending on it
+type Span interface {
+	// AddEvent adds an event with given name and flattened attributes
+	AddEvent(name string, attrs []slog.Attr)
+	// SetStatus sets the status of the span
+	SetStatus(code StatusCode, description string)
+}
+
+// SpanEvent is an error converted into a span event
+type SpanEvent struct {
+	// Name of the event
+	Name string
+	// Attrs are the flattened attributes of the event (no groups)
+	Attrs []slog.Attr
+	// Status is the status the span should be set to
+	Status StatusCode
+	// Description is the description of the status
+	Description string
+}
+
+// Attr returns the value of attribute with given key from the event
+func (s SpanEvent) Attr(key string) (slog.Value, bool) {
+	for _, a := range s.Attrs {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+// ToSpanEvent converts given error into a span event
+// it uses the kind, cause, chain and attributes of the error and
+// optionally includes the stack when available
+func ToSpanEvent(err error, includeStack bool) SpanEvent {
+	if err == nil {
+		return SpanEvent{Name: SpanEventName, Status: StatusUnset}
+	}
+	x := FromError(err)
+	event := SpanEvent{
+		Name:   SpanEventName,
+		Status: StatusError,
+		Attrs: []slog.Attr{
+			slog.String(AttrExceptionType, x.classifiedKind().String()),
+			slog.String(AttrExceptionMessage, x.Error()),
+		},
+	}
+	if cause := x.Cause(); cause != nil {
+		event.Description = cause.Error()
+		event.Attrs = append(event.Attrs, slog.String(AttrErrorCause, event.Description))
+	}
+	if len(x.errs) > 1 {
+		chain := make([]string, 0, len(x.errs)-1)
+		for _, e := range x.errs[1:] {
+			chain = append(chain, e.Error())
+		}
+		event.Attrs = append(event.Attrs, slog.String(AttrErrorChain, strings.Join(chain, ErrChainSeperator)))
+	}
+	event.Attrs = append(event.Attrs, flattenAttrs(AttrErrorAttrsPrefix, x.Attrs())...)
+	if includeStack {
+		if stack := x.Stack(); len(stack) > 0 {
+			event.Attrs = append(event.Attrs, slog.String(AttrExceptionStacktrace, stack.String()))
+		}
+	}
+	return event
+}
+
+// flattenAttrs flattens groups into dotted keys with given prefix
+func flattenAttrs(prefix string, attrs []slog.Attr) []slog.Attr {
+	flattened := []slog.Attr{}
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			flattened = append(flattened, flattenAttrs(prefix+a.Key+".", v.Group())...)
+			continue
+		}
+		flattened = append(flattened, slog.Attr{Key: prefix + a.Key, Value: v})
+	}
+	return flattened
+}
+
+// Exporter exports errors to an external system like a tracing backend
+type Exporter interface {
+	// Export exports given error in the scope of given context
+	Export(ctx context.Context, err error)
+}
+
+// NoopExporter is an exporter that does nothing
+type NoopExporter struct{}
+
+// Export implements Exporter
+func (NoopExporter) Export(ctx context.Context, err error) {}
+
+// SpanExporterOption is a configuration option for the SpanExporter
+type SpanExporterOption func(*SpanExporter)
+
+// WithStack includes the stack of the error in exported events
+func WithStack() SpanExporterOption {
+	return func(s *SpanExporter) {
+		s.includeStack = true
+	}
+}
+
+// SpanExporter exports errors as span events on the span returned for the context
+//
+//	exporter := errkit.NewSpanExporter(func(ctx context.Context) errkit.Span {
+//		return otelSpan{trace.SpanFromContext(ctx)}
+//	})
+type SpanExporter struct {
+	spanFromContext func(ctx context.Context) Span
+	includeStack    bool
+}
+
+// NewSpanExporter creates a new exporter using given function to get the active span
+func NewSpanExporter(spanFromContext func(ctx context.Context) Span, opts ...SpanExporterOption) *SpanExporter {
+	s := &SpanExporter{spanFromContext: spanFromContext}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Export implements Exporter
+func (s *SpanExporter) Export(ctx context.Context, err error) {
+	if err == nil || s.spanFromContext == nil {
+		return
+	}
+	span := s.spanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	event := ToSpanEvent(err, s.includeStack)
+	span.AddEvent(event.Name, event.Attrs)
+	span.SetStatus(event.Status, event.Description)
+}
+
+var (
+	exporterMu      sync.RWMutex
+	defaultExporter Exporter = NoopExporter{}
+)
+
+// SetExporter sets the exporter used by Export (default is NoopExporter)
+func SetExporter(exporter Exporter) {
+	if exporter == nil {
+		exporter = NoopExporter{}
+	}
+	exporterMu.Lock()
+	defer exporterMu.Unlock()
+	defaultExporter = exporter
+}
+
+// Export exports given error using the configured exporter
+func Export(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
+	exporterMu.RLock()
+	exporter := defaultExporter
+	exporterMu.RUnlock()
+	exporter.Export(ctx, err)
+}
+
+// InMemorySpan is a Span that records events and status in memory
+// it is meant to be used in tests to assert what was exported
+type InMemorySpan struct {
+	mu          sync.Mutex
+	events      []SpanEvent
+	status      StatusCode
+	description string
+}
+
+// NewInMemorySpan creates a new in-memory span
+func NewInMemorySpan() *InMemorySpan {
+	return &InMemorySpan{}
+}
+
+// AddEvent implements Span
+func (s *InMemorySpan) AddEvent(name string, attrs []slog.Attr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, SpanEvent{Name: name, Attrs: attrs})
+}
+
+// SetStatus implements Span
+func (s *InMemorySpan) SetStatus(code StatusCode, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.description = description
+}
+
+// Events returns all recorded events
+func (s *InMemorySpan) Events() []SpanEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]SpanEvent, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+// Status returns the recorded status and its description
+func (s *InMemorySpan) Status() (StatusCode, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status, s.description
+}
@@ -0,0 +1,73 @@
+package errkit
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanExporter(t *testing.T) {
+	span := NewInMemorySpan()
+	exporter := NewSpanExporter(func(ctx context.Context) Span { return span }, WithStack())
+
+	x := New("port closed or filtered", "port", 8080).SetKind(ErrKindNetworkPermanent)
+	x.source = &slog.Source{Function: "main.dial", File: "main.go", Line: 42}
+	err := WithAttr(Wrap(x, "could not connect"), slog.Group("retry", slog.Int("attempts", 2)))
+	exporter.Export(context.Background(), err)
+
+	events := span.Events()
+	require.Len(t, events, 1)
+	event := events[0]
+	require.Equal(t, SpanEventName, event.Name)
+
+	kind, ok := event.Attr(AttrExceptionType)
+	require.True(t, ok)
+	require.Equal(t, ErrKindNetworkPermanent.String(), kind.String())
+
+	cause, _ := event.Attr(AttrErrorCause)
+	require.Equal(t, "port closed or filtered", cause.String())
+	chain, _ := event.Attr(AttrErrorChain)
+	require.Equal(t, "could not connect", chain.String())
+
+	port, ok := event.Attr(AttrErrorAttrsPrefix + "port")
+	require.True(t, ok)
+	require.Equal(t, int64(8080), port.Int64())
+	attempts, ok := event.Attr(AttrErrorAttrsPrefix + "retry.attempts")
+	require.True(t, ok)
+	require.Equal(t, int64(2), attempts.Int64())
+
+	stack, ok := event.Attr(AttrExceptionStacktrace)
+	require.True(t, ok)
+	require.Contains(t, stack.String(), "main.go:42")
+
+	status, description := span.Status()
+	require.Equal(t, StatusError, status)
+	require.Equal(t, "port closed or filtered", description)
+}
+
+func TestDefaultExporter(t *testing.T) {
+	// default exporter is a no-op
+	Export(context.Background(), New("some error"))
+
+	span := NewInMemorySpan()
+	SetExporter(NewSpanExporter(func(ctx context.Context) Span { return span }))
+	defer SetExporter(nil)
+
+	Export(context.Background(), New("some error"))
+	Export(context.Background(), nil)
+	require.Len(t, span.Events(), 1)
+	_, ok := span.Events()[0].Attr(AttrExceptionStacktrace)
+	require.False(t, ok, "stack should not be exported by default")
+}
+
+func TestSpanEventClassifiesPlainErrors(t *testing.T) {
+	err := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	kind, ok := ToSpanEvent(err, false).Attr(AttrExceptionType)
+	require.True(t, ok)
+	require.Equal(t, GetErrorKind(err).String(), kind.String())
+	require.NotEqual(t, ErrKindUnknown.String(), kind.String())
+}
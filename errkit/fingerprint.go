@@ -0,0 +1,108 @@
+package errkit
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash"
+)
+
+// placeholders used while normalizing volatile parts of error messages
+const (
+	PlaceholderIP       = "<ip>"
+	PlaceholderPort     = "<port>"
+	PlaceholderHost     = "<host>"
+	PlaceholderDuration = "<duration>"
+	PlaceholderHex      = "<hex>"
+	PlaceholderUUID     = "<uuid>"
+)
+
+var (
+	reUUID          = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	reBracketedIPv6 = regexp.MustCompile(`\[[0-9a-fA-F:.%a-zA-Z]+\]`)
+	reIPv6          = regexp.MustCompile(`[0-9a-fA-F]{0,4}(?::[0-9a-fA-F]{0,4}){2,7}(?:%[a-zA-Z0-9]+)?`)
+	reIPv4          = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// hostnames are matched in lowercase only to avoid normalizing
+	// go identifiers like `Client.Timeout` present in error messages
+	reHostname = regexp.MustCompile(`\b(?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}\b`)
+	rePort     = regexp.MustCompile(`(` + regexp.QuoteMeta(PlaceholderIP) + `|` + regexp.QuoteMeta(PlaceholderHost) + `):\d{1,5}\b`)
+	reHex      = regexp.MustCompile(`\b(?:0x[0-9a-fA-F]+|[0-9a-fA-F]{8,})\b`)
+	reDuration = regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)(?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h))*\b`)
+)
+
+// NormalizeErrorMessage replaces volatile parts of given error message
+// (uuids, ip addresses, hostnames, ports, hex ids and durations) with
+// stable placeholders so similar errors result in the same message
+//
+//	dial tcp 10.0.0.1:443: i/o timeout after 1.5s => dial tcp <ip>:<port>: i/o timeout after <duration>
+func NormalizeErrorMessage(msg string) string {
+	msg = reUUID.ReplaceAllString(msg, PlaceholderUUID)
+	msg = reBracketedIPv6.ReplaceAllStringFunc(msg, func(s string) string {
+		if isIP(strings.Trim(s, "[]")) {
+			return PlaceholderIP
+		}
+		return s
+	})
+	msg = reIPv6.ReplaceAllStringFunc(msg, func(s string) string {
+		if isIP(s) {
+			return PlaceholderIP
+		}
+		return s
+	})
+	msg = reIPv4.ReplaceAllString(msg, PlaceholderIP)
+	msg = reHostname.ReplaceAllString(msg, PlaceholderHost)
+	msg = rePort.ReplaceAllString(msg, "$1:"+PlaceholderPort)
+	msg = reHex.ReplaceAllStringFunc(msg, func(s string) string {
+		// hex ids always contain at least one digit
+		if strings.HasPrefix(s, "0x") || strings.ContainsAny(s, "0123456789") {
+			return PlaceholderHex
+		}
+		return s
+	})
+	msg = reDuration.ReplaceAllString(msg, PlaceholderDuration)
+	return msg
+}
+
+func isIP(s string) bool {
+	if i := strings.Index(s, "%"); i > 0 {
+		// strip ipv6 zone
+		s = s[:i]
+	}
+	return net.ParseIP(s) != nil
+}
+
+// Fingerprint returns a stable key for the error which ignores volatile
+// parts of the error messages, errors with the same kind and normalized
+// error chain share the same fingerprint
+func (e *ErrorX) Fingerprint() string {
+	var sb strings.Builder
+	kinds := strings.Split(e.classifiedKind().String(), ",")
+	sort.Strings(kinds)
+	sb.WriteString(strings.Join(kinds, ","))
+	for _, err := range e.errs {
+		sb.WriteString("|")
+		sb.WriteString(NormalizeErrorMessage(err.Error()))
+	}
+	return fmt.Sprintf("%016x", xxhash.Sum64String(sb.String()))
+}
+
+// classifiedKind returns the kind of the error without mutating it
+// errors without an explicit kind are classified using GetErrorKind
+func (e *ErrorX) classifiedKind() ErrKind {
+	if e.kind != nil && e.kind.String() != "" {
+		return e.kind
+	}
+	return GetErrorKind(e)
+}
+
+// Fingerprint returns a stable fingerprint of given error
+// it returns empty string if error is nil
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	return FromError(err).Fingerprint()
+}
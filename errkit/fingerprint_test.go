@@ -0,0 +1,74 @@
+package errkit
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"dial tcp 10.0.0.1:443: i/o timeout", "dial tcp <ip>:<port>: i/o timeout"},
+		{"dial tcp [2001:db8::1]:80: connect: connection refused", "dial tcp <ip>:<port>: connect: connection refused"},
+		{"lookup scanme.sh on 8.8.8.8:53: no such host", "lookup <host> on <ip>:<port>: no such host"},
+		{"Get \"https://www.example.com:8443/path\": context deadline exceeded after 1.5s", "Get \"https://<host>:<port>/path\": context deadline exceeded after <duration>"},
+		{"request 5f2b9c0d1e failed with id 0xdeadbeef", "request <hex> failed with id <hex>"},
+		{"trace 123e4567-e89b-12d3-a456-426614174000 not found", "trace <uuid> not found"},
+		{"Client.Timeout exceeded while awaiting headers", "Client.Timeout exceeded while awaiting headers"},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.expected, NormalizeErrorMessage(test.input), test.input)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := New("dial tcp 10.0.0.1:443: i/o timeout").SetKind(ErrKindNetworkTemporary)
+	b := New("dial tcp 10.0.0.2:8443: i/o timeout").SetKind(ErrKindNetworkTemporary)
+	c := New("dial tcp 10.0.0.2:8443: i/o timeout").SetKind(ErrKindNetworkPermanent)
+	require.Equal(t, a.Fingerprint(), b.Fingerprint())
+	require.NotEqual(t, a.Fingerprint(), c.Fingerprint(), "different kinds should not share fingerprint")
+	require.Equal(t, Fingerprint(Wrap(a, "tcp dial error")), Fingerprint(Wrap(b, "tcp dial error")))
+	require.NotEqual(t, a.Fingerprint(), Fingerprint(Wrap(a, "tcp dial error")))
+	require.Empty(t, Fingerprint(nil))
+
+	// plain errors are classified and fingerprinting does not set a kind
+	plain := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	require.NotEqual(t, Fingerprint(errors.New(plain.Error())), Fingerprint(plain))
+	x := New("dial tcp 10.0.0.3:443: i/o timeout")
+	_ = x.Fingerprint()
+	require.Nil(t, x.kind)
+}
+
+func TestDedupSink(t *testing.T) {
+	sink := NewDedupSink(WithMaxSamples(2))
+	hosts := []string{"a.example.com", "b.example.com", "c.example.com"}
+	for _, host := range hosts {
+		err := New("lookup "+host+": no such host", "host", host)
+		sink.Add(err)
+	}
+	require.True(t, sink.Add(New("some other error")))
+	require.Equal(t, 2, sink.Len())
+
+	entries := sink.Entries()
+	require.Equal(t, 3, entries[0].Count)
+	require.Equal(t, "lookup <host>: no such host", entries[0].Message)
+	require.Len(t, entries[0].Samples, 2)
+	require.Equal(t, "a.example.com", entries[0].Samples[0]["host"])
+	require.False(t, entries[0].LastSeen.Before(entries[0].FirstSeen))
+
+	_, err := json.Marshal(entries)
+	require.NoError(t, err)
+
+	limited := NewDedupSink(WithMaxEntries(1))
+	limited.Add(New("first error"))
+	limited.Add(New("second error"))
+	require.Equal(t, 1, limited.Len())
+	require.Equal(t, 1, limited.Dropped())
+}
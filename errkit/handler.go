@@ -0,0 +1,153 @@
+package errkit
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// RedactedValue is the value used in place of redacted attributes
+const RedactedValue = "[REDACTED]"
+
+var _ slog.Handler = &Handler{}
+
+// HandlerOption is a configuration option for the Handler
+type HandlerOption func(*Handler)
+
+// WithRedactedKeys redacts values of attributes with given keys (case-insensitive)
+// it applies to record attributes as well as attributes of errors
+func WithRedactedKeys(keys ...string) HandlerOption {
+	return func(h *Handler) {
+		for _, key := range keys {
+			h.redact[strings.ToLower(key)] = struct{}{}
+		}
+	}
+}
+
+// WithHandlerKinds adds extra error kinds used while classifying errors
+func WithHandlerKinds(kinds ...ErrKind) HandlerOption {
+	return func(h *Handler) {
+		h.kinds = append(h.kinds, kinds...)
+	}
+}
+
+// Handler is a slog.Handler middleware that expands error valued attributes
+// into the errkit attribute group before forwarding records to the inner handler
+// so that plain `slog.Error("x", "err", err)` calls are logged with full structure
+//
+//	{
+//		"err": {
+//			"kind": "<error-kind>",
+//			"cause": "<cause>",
+//			"errors": [<errs>...],
+//			"attrs": {<error attrs>...},
+//			"stack": "<compact stack>" (only for debug records)
+//		}
+//	}
+type Handler struct {
+	inner  slog.Handler
+	redact map[string]struct{}
+	kinds  []ErrKind
+}
+
+// NewHandler creates a new handler wrapping given handler
+//
+//	logger := slog.New(errkit.NewHandler(slog.NewJSONHandler(os.Stderr, nil), errkit.WithRedactedKeys("password")))
+func NewHandler(inner slog.Handler, opts ...HandlerOption) *Handler {
+	h := &Handler{inner: inner, redact: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Enabled implements slog.Handler
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(h.transform(a, r.Level))
+		return true
+	})
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	transformed := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		// level of future records is unknown so stack is never included
+		transformed = append(transformed, h.transform(a, slog.LevelInfo))
+	}
+	return &Handler{inner: h.inner.WithAttrs(transformed), redact: h.redact, kinds: h.kinds}
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), redact: h.redact, kinds: h.kinds}
+}
+
+// transform expands errors and redacts sensitive attributes
+func (h *Handler) transform(a slog.Attr, level slog.Level) slog.Attr {
+	if h.isRedacted(a.Key) {
+		return slog.String(a.Key, RedactedValue)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make([]slog.Attr, 0, len(group))
+		for _, ga := range group {
+			attrs = append(attrs, h.transform(ga, level))
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok && err != nil {
+			return slog.Attr{Key: a.Key, Value: slog.GroupValue(h.errorAttrs(err, level)...)}
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// errorAttrs returns the attribute group for given error
+// it uses the same keys as ToSlogAttrs, the error string itself is not
+// logged since it contains unredacted error attributes
+func (h *Handler) errorAttrs(err error, level slog.Level) []slog.Attr {
+	x := FromError(err)
+	attrs := []slog.Attr{slog.String("kind", GetErrorKind(x, h.kinds...).String())}
+	if cause := x.Cause(); cause != nil {
+		attrs = append(attrs, slog.String("cause", cause.Error()))
+	}
+	if len(x.errs) > 0 {
+		values := make([]string, 0, len(x.errs))
+		for _, e := range x.errs {
+			values = append(values, e.Error())
+		}
+		attrs = append(attrs, slog.Any("errors", values))
+	}
+	if errAttrs := x.Attrs(); len(errAttrs) > 0 {
+		values := make([]slog.Attr, 0, len(errAttrs))
+		for _, ea := range errAttrs {
+			values = append(values, h.transform(ea, level))
+		}
+		attrs = append(attrs, slog.Attr{Key: "attrs", Value: slog.GroupValue(values...)})
+	}
+	if level <= slog.LevelDebug {
+		if stack := x.Stack(); len(stack) > 0 {
+			attrs = append(attrs, slog.String("stack", stack.Compact()))
+		}
+	}
+	return attrs
+}
+
+func (h *Handler) isRedacted(key string) bool {
+	if len(h.redact) == 0 {
+		return false
+	}
+	_, ok := h.redact[strings.ToLower(key)]
+	return ok
+}
@@ -0,0 +1,63 @@
+package errkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(NewHandler(inner, WithRedactedKeys("Password", "token")))
+
+	decode := func() map[string]any {
+		m := map[string]any{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+		buf.Reset()
+		return m
+	}
+
+	t.Run("Expands Errors", func(t *testing.T) {
+		err := With(New("port closed or filtered"), "address", "10.0.0.1", "password", "secret")
+		err = Wrap(err, "could not connect")
+		logger.Error("scan failed", "err", err, "token", "abc")
+
+		require.NotContains(t, buf.String(), "secret", "redacted values should not leak")
+		m := decode()
+		require.Equal(t, RedactedValue, m["token"])
+		errGroup, ok := m["err"].(map[string]any)
+		require.True(t, ok, "expected error to be expanded into group")
+		require.Equal(t, ErrKindNetworkPermanent.String(), errGroup["kind"])
+		require.Equal(t, "port closed or filtered", errGroup["cause"])
+		require.Equal(t, []any{"port closed or filtered", "could not connect"}, errGroup["errors"])
+		attrs := errGroup["attrs"].(map[string]any)
+		require.Equal(t, "10.0.0.1", attrs["address"])
+		require.Equal(t, RedactedValue, attrs["password"])
+		require.NotContains(t, errGroup, "stack")
+	})
+
+	t.Run("Plain Errors And Groups", func(t *testing.T) {
+		logger.With("component", "dialer").WithGroup("req").Info("request failed", "err", New("no such host"), "password", "x")
+		m := decode()
+		require.Equal(t, "dialer", m["component"])
+		req := m["req"].(map[string]any)
+		require.Equal(t, RedactedValue, req["password"])
+		require.Equal(t, ErrKindDNSNXDomain.String(), req["err"].(map[string]any)["kind"])
+	})
+
+	t.Run("Stack On Debug", func(t *testing.T) {
+		EnableTrace = true
+		defer func() { EnableTrace = false }()
+		err := New("i/o timeout")
+
+		logger.Info("failed", "err", err)
+		require.NotContains(t, decode()["err"].(map[string]any), "stack")
+
+		logger.Debug("failed", "err", err)
+		require.Contains(t, decode()["err"].(map[string]any)["stack"], "errkit.TestHandler")
+	})
+}
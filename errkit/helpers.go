@@ -19,7 +19,7 @@ func Is(err error, target ...error) bool {
 	return false
 }
 
-// IsKind checks if given error is equal to one of the given errkind
+// IsKind checks if given error is equal to (or a child of) one of the given errkind
 // if error did not already have a kind, it tries to parse it
 // using default error kinds and given kinds
 func IsKind(err error, match ...ErrKind) bool {
@@ -29,25 +29,24 @@ func IsKind(err error, match ...ErrKind) bool {
 	x := &ErrorX{}
 	parseError(x, err)
 	// try to parse kind from error
-	if x.kind == nil {
-		// parse kind from error
-		tmp := []ErrKind{ErrKindDeadline, ErrKindNetworkPermanent, ErrKindNetworkTemporary}
-		tmp = append(tmp, match...)
-		x.kind = GetErrorKind(err, tmp...)
+	if x.kind == nil || x.kind.String() == "" {
+		// parse kind from error, given kinds are tried first
+		// followed by all registered kinds
+		x.kind = GetErrorKind(err, match...)
 	}
 	if x.kind != nil {
 		if val, ok := x.kind.(*multiKind); ok && len(val.kinds) > 0 {
 			// if multi kind return first kind
 			for _, kind := range val.kinds {
 				for _, k := range match {
-					if k.Is(kind) {
+					if k.Is(kind) || k.IsParent(kind) {
 						return true
 					}
 				}
 			}
 		}
 		for _, kind := range match {
-			if kind.Is(x.kind) {
+			if kind.Is(x.kind) || kind.IsParent(x.kind) {
 				return true
 			}
 		}
@@ -268,7 +267,8 @@ func ToSlogAttrGroup(err error) slog.Attr {
 //		"cause": "<cause>",
 //		"errors": [
 //			<errs>...
-//		]
+//		],
+//		"stack": "<compact stack>" (only if captured)
 //	}
 func ToSlogAttrs(err error) []slog.Attr {
 	x := &ErrorX{}
@@ -283,6 +283,9 @@ func ToSlogAttrs(err error) []slog.Attr {
 	if len(x.errs) > 0 {
 		attrs = append(attrs, slog.Any("errors", x.errs))
 	}
+	if stack := x.Stack(); len(stack) > 0 {
+		attrs = append(attrs, slog.String("stack", stack.Compact()))
+	}
 	return attrs
 }
 
@@ -3,11 +3,13 @@ package errkit
 import "encoding/json"
 
 var (
-	_ json.Marshaler  = &ErrorX{}
-	_ JoinedError     = &ErrorX{}
-	_ CauseError      = &ErrorX{}
-	_ ComparableError = &ErrorX{}
-	_ error           = &ErrorX{}
+	_ json.Marshaler   = &ErrorX{}
+	_ json.Unmarshaler = &ErrorX{}
+	_ ConvertibleError = &ErrorX{}
+	_ JoinedError      = &ErrorX{}
+	_ CauseError       = &ErrorX{}
+	_ ComparableError  = &ErrorX{}
+	_ error            = &ErrorX{}
 )
 
 // below contains all interfaces that are implemented by ErrorX which
@@ -36,3 +38,10 @@ type WrappedError interface {
 	// Unwrap returns the underlying error
 	Unwrap() error
 }
+
+// ConvertibleError is implemented by errors that can be converted
+// into ErrorX without losing information (ex: legacy errorutil errors)
+type ConvertibleError interface {
+	// ToErrorX returns the ErrorX representation of the error
+	ToErrorX() *ErrorX
+}
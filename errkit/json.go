@@ -0,0 +1,199 @@
+package errkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// jsonErrorX is the serialized form of ErrorX
+type jsonErrorX struct {
+	Kind   string          `json:"kind"`
+	Errors []string        `json:"errors"`
+	Attrs  json.RawMessage `json:"attrs,omitempty"`
+	Source *slog.Source    `json:"source,omitempty"`
+	Stack  Stack           `json:"stack,omitempty"`
+	Time   *time.Time      `json:"time,omitempty"`
+}
+
+// jsonAttr is the serialized form of slog.Attr which
+// preserves the type of the value
+type jsonAttr struct {
+	Key   string          `json:"key"`
+	Kind  string          `json:"kind"`
+	Value json.RawMessage `json:"value"`
+}
+
+// UnmarshalJSON restores the error from its json representation created by MarshalJSON
+// the error chain is rebuilt in order, the kind is resolved using registered kinds
+// and attributes are restored along with their types
+func (e *ErrorX) UnmarshalJSON(data []byte) error {
+	var v jsonErrorX
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.Errors) == 0 {
+		return errors.New("errkit: cannot unmarshal error without any errors")
+	}
+	*e = ErrorX{record: &slog.Record{}}
+	for _, msg := range v.Errors {
+		e.append(errors.New(msg))
+	}
+	e.kind = parseKindString(v.Kind)
+	e.source = v.Source
+	e.frames = v.Stack
+	if v.Time != nil {
+		e.record.Time = *v.Time
+	}
+	// attrs created by older versions were not serialized as list and are ignored
+	if raw := bytes.TrimSpace(v.Attrs); len(raw) > 0 && raw[0] == '[' {
+		var attrs []jsonAttr
+		if err := json.Unmarshal(raw, &attrs); err != nil {
+			return err
+		}
+		values, err := unmarshalAttrs(attrs)
+		if err != nil {
+			return err
+		}
+		e.record.AddAttrs(values...)
+	}
+	return nil
+}
+
+// parseKindString resolves serialized kind (ex: `network-permanent-error,deadline-error`)
+// kinds that are not registered are recreated as primitive kinds with same id
+func parseKindString(value string) ErrKind {
+	if value == "" {
+		return nil
+	}
+	kinds := []ErrKind{}
+	for _, id := range strings.Split(value, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if kind, ok := KindByID(id); ok {
+			kinds = append(kinds, kind)
+		} else {
+			kinds = append(kinds, NewPrimitiveErrKind(id, "", nil))
+		}
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+	return CombineErrKinds(kinds...)
+}
+
+// marshalAttrs converts slog attributes into their typed json form
+func marshalAttrs(attrs []slog.Attr) ([]jsonAttr, error) {
+	values := make([]jsonAttr, 0, len(attrs))
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		var (
+			raw []byte
+			err error
+		)
+		switch v.Kind() {
+		case slog.KindString:
+			raw, err = json.Marshal(v.String())
+		case slog.KindInt64:
+			raw, err = json.Marshal(v.Int64())
+		case slog.KindUint64:
+			raw, err = json.Marshal(v.Uint64())
+		case slog.KindFloat64:
+			raw, err = json.Marshal(v.Float64())
+		case slog.KindBool:
+			raw, err = json.Marshal(v.Bool())
+		case slog.KindDuration:
+			raw, err = json.Marshal(int64(v.Duration()))
+		case slog.KindTime:
+			raw, err = json.Marshal(v.Time().Format(time.RFC3339Nano))
+		case slog.KindGroup:
+			var group []jsonAttr
+			group, err = marshalAttrs(v.Group())
+			if err == nil {
+				raw, err = json.Marshal(group)
+			}
+		default:
+			val := v.Any()
+			if e, ok := val.(error); ok {
+				raw, err = json.Marshal(e.Error())
+			} else {
+				raw, err = json.Marshal(val)
+				if err != nil {
+					// fallback to string representation for values that cannot be marshalled
+					raw, err = json.Marshal(fmt.Sprint(val))
+				}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, jsonAttr{Key: a.Key, Kind: v.Kind().String(), Value: raw})
+	}
+	return values, nil
+}
+
+// unmarshalAttrs restores slog attributes from their typed json form
+func unmarshalAttrs(values []jsonAttr) ([]slog.Attr, error) {
+	attrs := make([]slog.Attr, 0, len(values))
+	for _, value := range values {
+		var (
+			attr slog.Attr
+			err  error
+		)
+		switch value.Kind {
+		case slog.KindString.String():
+			var s string
+			err = json.Unmarshal(value.Value, &s)
+			attr = slog.String(value.Key, s)
+		case slog.KindInt64.String():
+			var i int64
+			err = json.Unmarshal(value.Value, &i)
+			attr = slog.Int64(value.Key, i)
+		case slog.KindUint64.String():
+			var u uint64
+			err = json.Unmarshal(value.Value, &u)
+			attr = slog.Uint64(value.Key, u)
+		case slog.KindFloat64.String():
+			var f float64
+			err = json.Unmarshal(value.Value, &f)
+			attr = slog.Float64(value.Key, f)
+		case slog.KindBool.String():
+			var b bool
+			err = json.Unmarshal(value.Value, &b)
+			attr = slog.Bool(value.Key, b)
+		case slog.KindDuration.String():
+			var d int64
+			err = json.Unmarshal(value.Value, &d)
+			attr = slog.Duration(value.Key, time.Duration(d))
+		case slog.KindTime.String():
+			var s string
+			var t time.Time
+			if err = json.Unmarshal(value.Value, &s); err == nil {
+				t, err = time.Parse(time.RFC3339Nano, s)
+			}
+			attr = slog.Time(value.Key, t)
+		case slog.KindGroup.String():
+			var group []jsonAttr
+			var groupAttrs []slog.Attr
+			if err = json.Unmarshal(value.Value, &group); err == nil {
+				groupAttrs, err = unmarshalAttrs(group)
+			}
+			attr = slog.Attr{Key: value.Key, Value: slog.GroupValue(groupAttrs...)}
+		default:
+			var a any
+			err = json.Unmarshal(value.Value, &a)
+			attr = slog.Any(value.Key, a)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not unmarshal attr %q: %w", value.Key, err)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
@@ -0,0 +1,70 @@
+package errkit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalError(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	x := New("port closed or filtered").SetKind(ErrKindNetworkPermanent)
+	x.source = &slog.Source{Function: "main.dial", File: "main.go", Line: 42}
+	var err error = WithAttr(x,
+		slog.String("address", "10.0.0.1"),
+		slog.Int("port", 443),
+		slog.Bool("tls", true),
+		slog.Float64("ratio", 0.5),
+		slog.Duration("timeout", 5*time.Second),
+		slog.Time("at", ts),
+		slog.Group("retry", slog.Int("attempts", 3)),
+	)
+	err = Wrap(err, "this is a wrapped error")
+
+	marshalled, merr := json.Marshal(err)
+	require.NoError(t, merr)
+
+	restored := &ErrorX{}
+	require.NoError(t, json.Unmarshal(marshalled, restored))
+
+	original := FromError(err)
+	require.Equal(t, original.Error(), restored.Error())
+	require.True(t, restored.Kind().Is(ErrKindNetworkPermanent))
+	require.True(t, IsKind(restored, ErrKindNetworkPermanent))
+	require.Len(t, restored.Errors(), 2)
+	require.Equal(t, x.source, restored.source)
+
+	require.Equal(t, slog.KindInt64, GetAttrValue(restored, "port").Kind())
+	require.Equal(t, int64(443), GetAttrValue(restored, "port").Int64())
+	require.True(t, GetAttrValue(restored, "tls").Bool())
+	require.Equal(t, 0.5, GetAttrValue(restored, "ratio").Float64())
+	require.Equal(t, 5*time.Second, GetAttrValue(restored, "timeout").Duration())
+	require.True(t, ts.Equal(GetAttrValue(restored, "at").Time()))
+	require.Equal(t, int64(3), groupValue(GetAttrValue(restored, "retry"), "attempts").Int64())
+
+	// marshalling restored error results in the same json
+	remarshalled, merr := json.Marshal(restored)
+	require.NoError(t, merr)
+	require.JSONEq(t, string(marshalled), string(remarshalled))
+}
+
+func TestUnmarshalUnregisteredKind(t *testing.T) {
+	custom := NewPrimitiveErrKind("custom-unregistered-error", "custom error", nil)
+	marshalled, err := json.Marshal(New("custom failure").SetKind(custom))
+	require.NoError(t, err)
+
+	restored := &ErrorX{}
+	require.NoError(t, json.Unmarshal(marshalled, restored))
+	require.Equal(t, "custom-unregistered-error", restored.Kind().String())
+	require.True(t, restored.Kind().Is(custom))
+}
+
+func TestUnmarshalEmptyErrors(t *testing.T) {
+	for _, data := range []string{`{"kind":"unknown-error","errors":[]}`, `{"kind":"unknown-error"}`} {
+		restored := &ErrorX{}
+		require.Error(t, json.Unmarshal([]byte(data), restored), data)
+	}
+}
@@ -3,8 +3,10 @@ package errkit
 import (
 	"context"
 	"errors"
+	"net"
 	"os"
 	"strings"
+	"syscall"
 
 	"golang.org/x/exp/maps"
 )
@@ -29,6 +31,8 @@ var (
 )
 
 var (
+	// Deprecated: register kinds using RegisterKind instead
+	//
 	// DefaultErrorKinds is the default error kinds used in classification
 	// if one intends to add more default error kinds it must be done in init() function
 	// of that package to avoid race conditions
@@ -61,6 +65,7 @@ var _ ErrKind = &primitiveErrKind{}
 type primitiveErrKind struct {
 	id         string
 	info       string
+	parent     ErrKind
 	represents func(*ErrorX) bool
 }
 
@@ -68,10 +73,24 @@ func (e *primitiveErrKind) Is(kind ErrKind) bool {
 	return e.id == kind.String()
 }
 
+// IsParent checks if current error kind is an ancestor of given error kind
 func (e *primitiveErrKind) IsParent(kind ErrKind) bool {
+	if kind == nil {
+		return false
+	}
+	for p := ParentKind(kind); p != nil; p = ParentKind(p) {
+		if e.Is(p) {
+			return true
+		}
+	}
 	return false
 }
 
+// Parent returns the parent error kind if any
+func (e *primitiveErrKind) Parent() ErrKind {
+	return e.parent
+}
+
 func (e *primitiveErrKind) Represents(err *ErrorX) bool {
 	if e.represents != nil {
 		return e.represents(err)
@@ -93,11 +112,44 @@ func NewPrimitiveErrKind(id string, info string, represents func(*ErrorX) bool)
 	return p
 }
 
-func isNetworkTemporaryErr(err *ErrorX) bool {
-	if err.Cause() != nil {
-		return os.IsTimeout(err.Cause())
+// NewChildErrKind creates a new primitive error kind nested under given parent
+//
+//	Example:
+//
+//	ErrKindDNSTimeout = errkit.NewChildErrKind("dns-timeout", "dns resolver timeout", errkit.ErrKindNetworkTemporary, isDNSTimeout)
+func NewChildErrKind(id string, info string, parent ErrKind, represents func(*ErrorX) bool) ErrKind {
+	p := &primitiveErrKind{id: id, info: info, parent: parent, represents: represents}
+	return p
+}
+
+// ParentKind returns the parent of given error kind
+// it returns nil if kind is not hierarchical or is a root kind
+func ParentKind(kind ErrKind) ErrKind {
+	if v, ok := kind.(interface{ Parent() ErrKind }); ok {
+		return v.Parent()
+	}
+	return nil
+}
+
+// KindDepth returns the depth of given error kind in its hierarchy
+// root kinds have depth 0
+func KindDepth(kind ErrKind) int {
+	depth := 0
+	for p := ParentKind(kind); p != nil; p = ParentKind(p) {
+		depth++
 	}
+	return depth
+}
+
+func isNetworkTemporaryErr(err *ErrorX) bool {
 	v := err.Cause()
+	if v == nil {
+		return false
+	}
+	// typed errors are checked before falling back to string matching
+	if netErr, ok := asError[net.Error](err); ok && netErr.Timeout() {
+		return true
+	}
 	switch {
 	case os.IsTimeout(v):
 		return true
@@ -112,8 +164,14 @@ func isNetworkPermanentErr(err *ErrorX) bool {
 	if err.Cause() == nil {
 		return false
 	}
+	// typed errors are checked before falling back to string matching
+	if dnsErr, ok := asError[*net.DNSError](err); ok && dnsErr.IsNotFound {
+		return true
+	}
+	if errors.Is(err.Cause(), syscall.ECONNREFUSED) || errors.Is(err.Cause(), syscall.EHOSTUNREACH) {
+		return true
+	}
 	v := err.Cause().Error()
-	// to implement
 	switch {
 	case strings.Contains(v, "no address found"):
 		return true
@@ -257,7 +315,8 @@ func CombineErrKinds(kind ...ErrKind) ErrKind {
 func GetErrorKind(err error, defs ...ErrKind) ErrKind {
 	x := &ErrorX{}
 	parseError(x, err)
-	if x.kind != nil {
+	// note: an empty multiKind is not a valid kind and is ignored
+	if x.kind != nil && x.kind.String() != "" {
 		if val, ok := x.kind.(*multiKind); ok && len(val.kinds) > 0 {
 			// if multi kind return first kind
 			return val.kinds[0]
@@ -271,6 +330,10 @@ func GetErrorKind(err error, defs ...ErrKind) ErrKind {
 			return def
 		}
 	}
+	// check in registered error kinds
+	if kind := DefaultKindRegistry.Classify(x); kind != nil {
+		return kind
+	}
 	// check in default error kinds
 	for _, def := range DefaultErrorKinds {
 		if def.Represents(x) {
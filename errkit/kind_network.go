@@ -0,0 +1,225 @@
+package errkit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+var (
+	// ErrKindTLSHandshake indicates a failure during tls handshake
+	// ex: remote error: tls: handshake failure
+	ErrKindTLSHandshake = NewChildErrKind("tls-handshake-error", "tls handshake error", ErrKindNetworkPermanent, isTLSHandshakeErr)
+	// ErrKindTLSHandshakeTimeout indicates the tls handshake did not complete in time
+	// ex: net/http: TLS handshake timeout
+	ErrKindTLSHandshakeTimeout = NewChildErrKind("tls-handshake-timeout-error", "tls handshake timeout", ErrKindNetworkTemporary, isTLSHandshakeTimeoutErr)
+	// ErrKindTLSUnknownAuthority indicates the certificate was signed by an unknown authority
+	ErrKindTLSUnknownAuthority = NewChildErrKind("tls-unknown-authority-error", "tls certificate signed by unknown authority", ErrKindTLSHandshake, isTLSUnknownAuthorityErr)
+	// ErrKindTLSBadCertificate indicates an invalid certificate (expired, hostname mismatch etc)
+	ErrKindTLSBadCertificate = NewChildErrKind("tls-bad-certificate-error", "tls bad certificate", ErrKindTLSHandshake, isTLSBadCertificateErr)
+	// ErrKindTLSProtocolVersion indicates that no common tls protocol version is supported
+	ErrKindTLSProtocolVersion = NewChildErrKind("tls-protocol-version-error", "tls protocol version not supported", ErrKindTLSHandshake, isTLSProtocolVersionErr)
+
+	// ErrKindDNSNXDomain indicates that the domain does not exist
+	// ex: lookup example.invalid: no such host
+	ErrKindDNSNXDomain = NewChildErrKind("dns-nxdomain-error", "dns domain does not exist", ErrKindNetworkPermanent, isDNSNXDomainErr)
+	// ErrKindDNSServFail indicates the resolver failed to answer the query
+	// ex: lookup example.com: server misbehaving
+	ErrKindDNSServFail = NewChildErrKind("dns-servfail-error", "dns server failure", ErrKindNetworkTemporary, isDNSServFailErr)
+	// ErrKindDNSTimeout indicates the resolver did not answer in time
+	ErrKindDNSTimeout = NewChildErrKind("dns-timeout-error", "dns resolver timeout", ErrKindNetworkTemporary, isDNSTimeoutErr)
+
+	// ErrKindHTTP indicates a failure in the http protocol layer
+	ErrKindHTTP = NewChildErrKind("http-error", "http protocol error", ErrKindNetworkPermanent, nil)
+	// ErrKindHTTPMalformedResponse indicates that the server sent an invalid http response
+	ErrKindHTTPMalformedResponse = NewChildErrKind("http-malformed-response-error", "malformed http response", ErrKindHTTP, isHTTPMalformedResponseErr)
+	// ErrKindHTTPBodyTooLarge indicates that the body exceeded the configured limit
+	ErrKindHTTPBodyTooLarge = NewChildErrKind("http-body-too-large-error", "http body too large", ErrKindHTTP, isHTTPBodyTooLargeErr)
+	// ErrKindHTTPRedirectLoop indicates that max redirects were exceeded
+	ErrKindHTTPRedirectLoop = NewChildErrKind("http-redirect-loop-error", "http redirect loop", ErrKindHTTP, isHTTPRedirectLoopErr)
+
+	// ErrKindProxy indicates a failure while connecting through a proxy
+	ErrKindProxy = NewChildErrKind("proxy-error", "proxy error", ErrKindNetworkPermanent, nil)
+	// ErrKindProxyAuthRequired indicates that the proxy requires (valid) authentication
+	ErrKindProxyAuthRequired = NewChildErrKind("proxy-auth-required-error", "proxy authentication required", ErrKindProxy, isProxyAuthRequiredErr)
+	// ErrKindProxyConnectRefused indicates that the proxy refused the connection
+	ErrKindProxyConnectRefused = NewChildErrKind("proxy-connect-refused-error", "proxy connection refused", ErrKindProxy, isProxyConnectRefusedErr)
+)
+
+// networkErrKinds are the built-in network error kinds in registration order
+// (parents must be registered before children)
+var networkErrKinds = []ErrKind{
+	ErrKindTLSHandshake,
+	ErrKindTLSHandshakeTimeout,
+	ErrKindTLSUnknownAuthority,
+	ErrKindTLSBadCertificate,
+	ErrKindTLSProtocolVersion,
+	ErrKindDNSNXDomain,
+	ErrKindDNSServFail,
+	ErrKindDNSTimeout,
+	ErrKindHTTP,
+	ErrKindHTTPMalformedResponse,
+	ErrKindHTTPBodyTooLarge,
+	ErrKindHTTPRedirectLoop,
+	ErrKindProxy,
+	ErrKindProxyAuthRequired,
+	ErrKindProxyConnectRefused,
+}
+
+// asError finds the first error (including unwrapped wrappers) of type T
+func asError[T any](x *ErrorX) (T, bool) {
+	var target T
+	for _, err := range x.errs {
+		if errors.As(err, &target) {
+			return target, true
+		}
+	}
+	for _, err := range x.wrapped {
+		if errors.As(err, &target) {
+			return target, true
+		}
+	}
+	return target, false
+}
+
+// containsAny checks if any error in the chain contains one of given substrings
+func containsAny(x *ErrorX, substrs ...string) bool {
+	for _, err := range x.errs {
+		msg := err.Error()
+		for _, s := range substrs {
+			if strings.Contains(msg, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isTLSHandshakeErr(x *ErrorX) bool {
+	// timeouts are temporary (see isTLSHandshakeTimeoutErr)
+	if isTLSHandshakeTimeoutErr(x) {
+		return false
+	}
+	if _, ok := asError[tls.RecordHeaderError](x); ok {
+		return true
+	}
+	if _, ok := asError[tls.AlertError](x); ok {
+		return true
+	}
+	if _, ok := asError[*tls.CertificateVerificationError](x); ok {
+		return true
+	}
+	return containsAny(x, "tls: handshake failure", "remote error: tls:", "tls: first record does not look like a TLS handshake", "TLS handshake")
+}
+
+func isTLSHandshakeTimeoutErr(x *ErrorX) bool {
+	if !containsAny(x, "TLS handshake timeout", "tls: handshake timeout") {
+		return false
+	}
+	if netErr, ok := asError[net.Error](x); ok {
+		return netErr.Timeout()
+	}
+	return true
+}
+
+func isTLSUnknownAuthorityErr(x *ErrorX) bool {
+	if _, ok := asError[x509.UnknownAuthorityError](x); ok {
+		return true
+	}
+	if alert, ok := asError[tls.AlertError](x); ok && alert == 48 {
+		// unknown_ca(48)
+		return true
+	}
+	return containsAny(x, "certificate signed by unknown authority")
+}
+
+func isTLSBadCertificateErr(x *ErrorX) bool {
+	if _, ok := asError[x509.CertificateInvalidError](x); ok {
+		return true
+	}
+	if _, ok := asError[x509.HostnameError](x); ok {
+		return true
+	}
+	if alert, ok := asError[tls.AlertError](x); ok {
+		// bad_certificate(42) to access_denied(47), unknown_ca(48) is an unknown authority
+		return alert >= 42 && alert <= 47
+	}
+	return containsAny(x, "tls: bad certificate", "x509: certificate has expired", "x509: certificate is valid for", "x509: certificate is not valid")
+}
+
+func isTLSProtocolVersionErr(x *ErrorX) bool {
+	if alert, ok := asError[tls.AlertError](x); ok {
+		// protocol_version(70)
+		return alert == 70
+	}
+	return containsAny(x, "tls: protocol version not supported", "unsupported protocol version", "tls: no supported versions")
+}
+
+func isDNSNXDomainErr(x *ErrorX) bool {
+	if dnsErr, ok := asError[*net.DNSError](x); ok {
+		return dnsErr.IsNotFound
+	}
+	return containsAny(x, "no such host", "NXDOMAIN")
+}
+
+func isDNSServFailErr(x *ErrorX) bool {
+	if dnsErr, ok := asError[*net.DNSError](x); ok {
+		return !dnsErr.IsNotFound && !dnsErr.IsTimeout && strings.Contains(dnsErr.Err, "server misbehaving")
+	}
+	return containsAny(x, "server misbehaving", "SERVFAIL")
+}
+
+func isDNSTimeoutErr(x *ErrorX) bool {
+	if dnsErr, ok := asError[*net.DNSError](x); ok {
+		return dnsErr.IsTimeout
+	}
+	for _, err := range x.errs {
+		msg := err.Error()
+		if strings.HasPrefix(msg, "lookup ") && strings.Contains(msg, "timeout") {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPMalformedResponseErr(x *ErrorX) bool {
+	return containsAny(x, "malformed HTTP response", "malformed HTTP status code", "malformed HTTP version", "malformed MIME header", "transport connection broken: malformed")
+}
+
+func isHTTPBodyTooLargeErr(x *ErrorX) bool {
+	if _, ok := asError[*http.MaxBytesError](x); ok {
+		return true
+	}
+	return containsAny(x, "body too large", "http: request body too large")
+}
+
+func isHTTPRedirectLoopErr(x *ErrorX) bool {
+	for _, err := range x.errs {
+		msg := err.Error()
+		if strings.Contains(msg, "stopped after") && strings.Contains(msg, "redirects") {
+			return true
+		}
+	}
+	return containsAny(x, "redirect loop")
+}
+
+func isProxyAuthRequiredErr(x *ErrorX) bool {
+	return containsAny(x, "Proxy Authentication Required", "username/password authentication failed", "proxy authentication required")
+}
+
+func isProxyConnectRefusedErr(x *ErrorX) bool {
+	if opErr, ok := asError[*net.OpError](x); ok && opErr.Op == "proxyconnect" {
+		return errors.Is(opErr, syscall.ECONNREFUSED) || strings.Contains(opErr.Error(), "connection refused")
+	}
+	for _, err := range x.errs {
+		msg := err.Error()
+		if strings.Contains(msg, "proxyconnect") && strings.Contains(msg, "connection refused") {
+			return true
+		}
+	}
+	return false
+}
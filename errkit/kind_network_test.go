@@ -0,0 +1,151 @@
+package errkit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkErrKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrKind
+		parent   ErrKind
+	}{
+		{
+			name:     "dns nxdomain typed",
+			err:      &url.Error{Op: "Get", URL: "https://example.invalid", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}}},
+			expected: ErrKindDNSNXDomain,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "dns nxdomain string",
+			err:      errors.New("lookup example.invalid: no such host"),
+			expected: ErrKindDNSNXDomain,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "dns timeout typed",
+			err:      &net.DNSError{Err: "i/o timeout", Name: "example.com", Server: "8.8.8.8:53", IsTimeout: true},
+			expected: ErrKindDNSTimeout,
+			parent:   ErrKindNetworkTemporary,
+		},
+		{
+			name:     "dns servfail typed",
+			err:      &net.DNSError{Err: "server misbehaving", Name: "example.com"},
+			expected: ErrKindDNSServFail,
+			parent:   ErrKindNetworkTemporary,
+		},
+		{
+			name:     "tls unknown authority typed",
+			err:      fmt.Errorf("tls: failed to verify certificate: %w", x509.UnknownAuthorityError{}),
+			expected: ErrKindTLSUnknownAuthority,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "tls bad certificate typed",
+			err:      x509.CertificateInvalidError{Reason: x509.Expired},
+			expected: ErrKindTLSBadCertificate,
+			parent:   ErrKindTLSHandshake,
+		},
+		{
+			name:     "tls unknown ca alert",
+			err:      &net.OpError{Op: "remote error", Err: tls.AlertError(48)},
+			expected: ErrKindTLSUnknownAuthority,
+			parent:   ErrKindTLSHandshake,
+		},
+		{
+			name:     "tls bad certificate alert",
+			err:      &net.OpError{Op: "remote error", Err: tls.AlertError(45)},
+			expected: ErrKindTLSBadCertificate,
+			parent:   ErrKindTLSHandshake,
+		},
+		{
+			name:     "tls protocol version alert",
+			err:      &net.OpError{Op: "remote error", Err: tls.AlertError(70)},
+			expected: ErrKindTLSProtocolVersion,
+			parent:   ErrKindTLSHandshake,
+		},
+		{
+			name:     "tls handshake string",
+			err:      errors.New("remote error: tls: handshake failure"),
+			expected: ErrKindTLSHandshake,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "tls handshake timeout typed",
+			err:      &url.Error{Op: "Get", URL: "https://example.com", Err: tlsHandshakeTimeoutErr{}},
+			expected: ErrKindTLSHandshakeTimeout,
+			parent:   ErrKindNetworkTemporary,
+		},
+		{
+			name:     "http malformed response",
+			err:      errors.New(`net/http: HTTP/1.x transport connection broken: malformed HTTP response "\x00\x00"`),
+			expected: ErrKindHTTPMalformedResponse,
+			parent:   ErrKindHTTP,
+		},
+		{
+			name:     "http body too large typed",
+			err:      &http.MaxBytesError{Limit: 10},
+			expected: ErrKindHTTPBodyTooLarge,
+			parent:   ErrKindHTTP,
+		},
+		{
+			name:     "http redirect loop",
+			err:      &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("stopped after 10 redirects")},
+			expected: ErrKindHTTPRedirectLoop,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "proxy auth required",
+			err:      &url.Error{Op: "Get", URL: "https://example.com", Err: errors.New("Proxy Authentication Required")},
+			expected: ErrKindProxyAuthRequired,
+			parent:   ErrKindProxy,
+		},
+		{
+			name:     "proxy connect refused typed",
+			err:      &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}},
+			expected: ErrKindProxyConnectRefused,
+			parent:   ErrKindNetworkPermanent,
+		},
+		{
+			name:     "connection refused typed",
+			err:      &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}},
+			expected: ErrKindNetworkPermanent,
+			parent:   ErrKindNetworkPermanent,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kind := GetErrorKind(test.err)
+			require.Equal(t, test.expected.String(), kind.String())
+			require.True(t, IsKind(test.err, test.expected))
+			require.True(t, IsKind(test.err, test.parent), "expected kind to be nested under %v", test.parent)
+		})
+	}
+}
+
+// tlsHandshakeTimeoutErr mimics the unexported error returned by net/http
+type tlsHandshakeTimeoutErr struct{}
+
+func (tlsHandshakeTimeoutErr) Timeout() bool   { return true }
+func (tlsHandshakeTimeoutErr) Temporary() bool { return true }
+func (tlsHandshakeTimeoutErr) Error() string   { return "net/http: TLS handshake timeout" }
+
+func TestParseErrorPreservesType(t *testing.T) {
+	dnsErr := &net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}
+	x := FromError(dnsErr)
+	var target *net.DNSError
+	require.True(t, errors.As(x, &target))
+	require.Equal(t, dnsErr.Error(), x.Cause().Error())
+}
@@ -0,0 +1,136 @@
+package errkit
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultKindRegistry is the registry used by errkit for classification
+// and lookups, it contains all built-in error kinds
+var DefaultKindRegistry = newDefaultKindRegistry()
+
+func newDefaultKindRegistry() *KindRegistry {
+	r := NewKindRegistry()
+	r.MustRegister(
+		ErrKindNetworkTemporary,
+		ErrKindNetworkPermanent,
+		ErrKindDeadline,
+		ErrKindUnknown,
+	)
+	r.MustRegister(networkErrKinds...)
+	r.MustRegister(ErrKindCircuitOpen)
+	return r
+}
+
+// KindRegistry is a thread-safe registry of error kinds
+// kinds are registered with an optional parent which must already
+// be registered, this allows building a hierarchy like
+//
+//	network-temporary-error -> dns-error -> dns-timeout
+type KindRegistry struct {
+	mu    sync.RWMutex
+	kinds map[string]ErrKind
+	// order keeps registration order for deterministic classification
+	order []ErrKind
+}
+
+// NewKindRegistry creates a new empty kind registry
+func NewKindRegistry() *KindRegistry {
+	return &KindRegistry{kinds: map[string]ErrKind{}}
+}
+
+// Register registers given error kinds in the registry
+// it returns an error if a kind is already registered or its parent is not
+func (r *KindRegistry) Register(kinds ...ErrKind) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		if kind == nil || kind.String() == "" {
+			return New("cannot register empty error kind")
+		}
+		if _, ok := kind.(*multiKind); ok {
+			return New("cannot register combined error kind", "kind", kind.String())
+		}
+		if _, ok := r.kinds[kind.String()]; ok {
+			return New("error kind already registered", "kind", kind.String())
+		}
+		if parent := ParentKind(kind); parent != nil {
+			if _, ok := r.kinds[parent.String()]; !ok {
+				return New("parent error kind is not registered", "kind", kind.String(), "parent", parent.String())
+			}
+		}
+		r.kinds[kind.String()] = kind
+		r.order = append(r.order, kind)
+	}
+	return nil
+}
+
+// MustRegister registers given error kinds and panics on failure
+func (r *KindRegistry) MustRegister(kinds ...ErrKind) {
+	if err := r.Register(kinds...); err != nil {
+		panic(fmt.Sprintf("errkit: %v", err))
+	}
+}
+
+// Get returns the error kind registered with given id
+func (r *KindRegistry) Get(id string) (ErrKind, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kind, ok := r.kinds[id]
+	return kind, ok
+}
+
+// Kinds returns all registered error kinds in registration order
+func (r *KindRegistry) Kinds() []ErrKind {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]ErrKind, len(r.order))
+	copy(kinds, r.order)
+	return kinds
+}
+
+// Children returns all registered kinds that are direct children of given kind
+func (r *KindRegistry) Children(kind ErrKind) []ErrKind {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	children := []ErrKind{}
+	for _, k := range r.order {
+		if parent := ParentKind(k); parent != nil && parent.Is(kind) {
+			children = append(children, k)
+		}
+	}
+	return children
+}
+
+// Classify returns the most specific registered kind that represents given error
+// when multiple kinds match, the deepest one in the hierarchy wins and ties are
+// resolved by registration order. It returns nil if no kind matches
+func (r *KindRegistry) Classify(err *ErrorX) ErrKind {
+	if err == nil {
+		return nil
+	}
+	var (
+		found ErrKind
+		depth = -1
+	)
+	for _, kind := range r.Kinds() {
+		if !kind.Represents(err) {
+			continue
+		}
+		if d := KindDepth(kind); d > depth {
+			found, depth = kind, d
+		}
+	}
+	return found
+}
+
+// RegisterKind registers given error kinds in the default registry
+// it should be called from init() or package level variables
+func RegisterKind(kinds ...ErrKind) error {
+	return DefaultKindRegistry.Register(kinds...)
+}
+
+// KindByID returns the error kind registered in default registry with given id
+func KindByID(id string) (ErrKind, bool) {
+	return DefaultKindRegistry.Get(id)
+}
@@ -0,0 +1,91 @@
+package errkit
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindRegistry(t *testing.T) {
+	registry := NewKindRegistry()
+	require.NoError(t, registry.Register(ErrKindNetworkTemporary))
+
+	dns := NewChildErrKind("dns-error", "dns error", ErrKindNetworkTemporary, nil)
+	dnsTimeout := NewChildErrKind("dns-timeout", "dns resolver timeout", dns, func(x *ErrorX) bool {
+		return strings.Contains(x.Cause().Error(), "dns timeout")
+	})
+
+	t.Run("Parent Must Be Registered", func(t *testing.T) {
+		require.Error(t, registry.Register(dnsTimeout))
+		require.NoError(t, registry.Register(dns, dnsTimeout))
+		require.Error(t, registry.Register(dns), "duplicate registration should fail")
+	})
+
+	t.Run("Lookup And Hierarchy", func(t *testing.T) {
+		kind, ok := registry.Get("dns-timeout")
+		require.True(t, ok)
+		require.True(t, kind.Is(dnsTimeout))
+		require.Equal(t, 2, KindDepth(kind))
+		require.True(t, ErrKindNetworkTemporary.IsParent(dnsTimeout))
+		require.True(t, dns.IsParent(dnsTimeout))
+		require.False(t, dnsTimeout.IsParent(dns))
+		require.Len(t, registry.Children(dns), 1)
+	})
+
+	t.Run("Classify Most Specific", func(t *testing.T) {
+		x := FromError(New("dns timeout while resolving"))
+		kind := registry.Classify(x)
+		require.NotNil(t, kind)
+		require.True(t, kind.Is(dnsTimeout))
+	})
+
+	t.Run("IsKind Matches Parents", func(t *testing.T) {
+		err := New("dns timeout while resolving").SetKind(dnsTimeout)
+		require.True(t, IsKind(err, ErrKindNetworkTemporary))
+		require.True(t, IsKind(err, dns))
+		require.False(t, IsKind(err, ErrKindNetworkPermanent))
+	})
+
+	t.Run("Default Registry", func(t *testing.T) {
+		kind, ok := KindByID(ErrKindDeadline.String())
+		require.True(t, ok)
+		require.True(t, kind.Is(ErrKindDeadline))
+	})
+}
+
+func TestKindStats(t *testing.T) {
+	dnsTimeout := NewChildErrKind("stats-dns-timeout", "dns resolver timeout", ErrKindNetworkTemporary, nil)
+	stats := NewKindStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			switch {
+			case i < 5:
+				stats.Record(New("dns timeout").SetKind(dnsTimeout))
+			case i < 8:
+				stats.Record(New("i/o timeout").SetKind(ErrKindNetworkTemporary))
+			default:
+				stats.Record(New("no such host"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 5, stats.Count(dnsTimeout))
+	require.Equal(t, 8, stats.Count(ErrKindNetworkTemporary))
+	require.Equal(t, 2, stats.Count(ErrKindNetworkPermanent))
+
+	top := stats.Top(2)
+	require.Len(t, top, 2)
+	require.True(t, top[0].Kind.Is(dnsTimeout))
+	require.True(t, top[1].Kind.Is(ErrKindNetworkTemporary))
+	require.Equal(t, 3, top[1].Count)
+	require.Equal(t, 8, top[1].Total)
+
+	require.Contains(t, stats.Summary(1), "dns resolver timeout (stats-dns-timeout): 5")
+}
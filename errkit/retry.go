@@ -0,0 +1,229 @@
+package errkit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy defines how errors of a particular kind are retried
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts (including the first one)
+	MaxAttempts int
+	// InitialDelay is the delay before the first retry
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between two attempts (0 means no cap)
+	MaxDelay time.Duration
+	// Multiplier is the exponential backoff factor applied after each attempt
+	// values <= 1 result in a constant delay
+	Multiplier float64
+	// Jitter is the fraction (0-1) of the delay that is randomized
+	// ex: 0.2 means the delay is randomly reduced by up to 20%
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the policy used for temporary network errors
+// when no custom policy is configured
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     10 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Delay returns the backoff delay to wait after given attempt (1-indexed)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 || p.InitialDelay <= 0 {
+		return 0
+	}
+	delay := float64(p.InitialDelay)
+	if p.Multiplier > 1 {
+		delay *= math.Pow(p.Multiplier, float64(attempt-1))
+	}
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+	// without a cap large attempt counts overflow time.Duration (or reach +Inf)
+	if delay >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// RetryOption is a configuration option for the Retrier
+type RetryOption func(*Retrier)
+
+// WithRetryPolicy sets the retry policy for given error kind
+// the policy also applies to all child kinds of given kind
+func WithRetryPolicy(kind ErrKind, policy RetryPolicy) RetryOption {
+	return func(r *Retrier) {
+		for i, rp := range r.policies {
+			if rp.kind.Is(kind) {
+				r.policies[i].policy = policy
+				return
+			}
+		}
+		r.policies = append(r.policies, kindPolicy{kind: kind, policy: policy})
+	}
+}
+
+// WithoutRetry disables retries for given error kind
+func WithoutRetry(kind ErrKind) RetryOption {
+	return WithRetryPolicy(kind, RetryPolicy{MaxAttempts: 1})
+}
+
+// WithFallbackPolicy sets the retry policy used for errors whose kind
+// does not match any configured policy (by default such errors are not retried)
+func WithFallbackPolicy(policy RetryPolicy) RetryOption {
+	return func(r *Retrier) {
+		r.fallback = &policy
+	}
+}
+
+// WithRetryKinds adds extra error kinds used while classifying errors
+func WithRetryKinds(kinds ...ErrKind) RetryOption {
+	return func(r *Retrier) {
+		r.kinds = append(r.kinds, kinds...)
+	}
+}
+
+// WithOnRetry sets a callback invoked before each retry
+func WithOnRetry(fn func(attempt int, err error, delay time.Duration)) RetryOption {
+	return func(r *Retrier) {
+		r.onRetry = fn
+	}
+}
+
+type kindPolicy struct {
+	kind   ErrKind
+	policy RetryPolicy
+}
+
+// Retrier retries operations based on the kind of error they return
+// errors are classified using GetErrorKind and the first policy matching
+// the kind (or one of its parents) decides if and when to retry
+type Retrier struct {
+	policies []kindPolicy
+	fallback *RetryPolicy
+	kinds    []ErrKind
+	onRetry  func(attempt int, err error, delay time.Duration)
+}
+
+// NewRetrier creates a new retrier with given options
+// by default only temporary network errors are retried using DefaultRetryPolicy
+func NewRetrier(opts ...RetryOption) *Retrier {
+	r := &Retrier{}
+	WithRetryPolicy(ErrKindNetworkTemporary, DefaultRetryPolicy)(r)
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// PolicyFor returns the retry policy for given error kind if any
+func (r *Retrier) PolicyFor(kind ErrKind) (RetryPolicy, bool) {
+	if kind == nil {
+		return RetryPolicy{}, false
+	}
+	// exact matches take precedence over parent matches
+	for _, rp := range r.policies {
+		if rp.kind.Is(kind) {
+			return rp.policy, true
+		}
+	}
+	for _, rp := range r.policies {
+		if rp.kind.IsParent(kind) {
+			return rp.policy, true
+		}
+	}
+	if r.fallback != nil {
+		return *r.fallback, true
+	}
+	return RetryPolicy{}, false
+}
+
+// Do executes given function until it succeeds, returns a non-retryable error,
+// exhausts the attempts of the matching policy or the context is done.
+// The returned error is an ErrorX with a `retry` attribute group describing
+// every attempt and the reason for giving up
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var attempts []slog.Attr
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return r.giveUp(ctxErr, ErrKindDeadline, attempts, "context done")
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		kind := GetErrorKind(err, r.kinds...)
+		policy, ok := r.PolicyFor(kind)
+		if !ok {
+			attempts = append(attempts, attemptAttr(attempt, kind, err, 0))
+			return r.giveUp(err, kind, attempts, "non-retryable error kind")
+		}
+		if attempt >= policy.MaxAttempts {
+			attempts = append(attempts, attemptAttr(attempt, kind, err, 0))
+			return r.giveUp(err, kind, attempts, "max attempts exceeded")
+		}
+		delay := policy.Delay(attempt)
+		attempts = append(attempts, attemptAttr(attempt, kind, err, delay))
+		if r.onRetry != nil {
+			r.onRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return r.giveUp(err, kind, attempts, "context done")
+		case <-timer.C:
+		}
+	}
+}
+
+// giveUp returns the final error along with retry attributes
+func (r *Retrier) giveUp(err error, kind ErrKind, attempts []slog.Attr, reason string) error {
+	x := FromError(err)
+	if (x.kind == nil || x.kind.String() == "") && kind != nil && !kind.Is(ErrKindUnknown) {
+		x.kind = kind
+	}
+	x.init()
+	args := []any{
+		slog.Int("attempts", len(attempts)),
+		slog.String("reason", reason),
+	}
+	for _, a := range attempts {
+		args = append(args, a)
+	}
+	x.record.AddAttrs(slog.Group("retry", args...))
+	return x
+}
+
+func attemptAttr(attempt int, kind ErrKind, err error, delay time.Duration) slog.Attr {
+	args := []any{
+		slog.String("kind", kind.String()),
+		slog.String("error", Cause(err).Error()),
+	}
+	if delay > 0 {
+		args = append(args, slog.Duration("delay", delay))
+	}
+	return slog.Group(fmt.Sprintf("attempt_%d", attempt), args...)
+}
+
+// Retry executes given function using a retrier with given options
+//
+//	err := errkit.Retry(ctx, func(ctx context.Context) error {
+//		return dial(ctx, host)
+//	})
+func Retry(ctx context.Context, fn func(ctx context.Context) error, opts ...RetryOption) error {
+	return NewRetrier(opts...).Do(ctx, fn)
+}
@@ -0,0 +1,108 @@
+package errkit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrier(t *testing.T) {
+	fastPolicy := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2}
+
+	t.Run("Retry Temporary Until Success", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return New("i/o timeout").SetKind(ErrKindNetworkTemporary)
+			}
+			return nil
+		}, WithRetryPolicy(ErrKindNetworkTemporary, fastPolicy))
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Give Up After Max Attempts", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			return New("i/o timeout").SetKind(ErrKindNetworkTemporary)
+		}, WithRetryPolicy(ErrKindNetworkTemporary, fastPolicy))
+		require.Error(t, err)
+		require.Equal(t, 3, calls)
+		require.True(t, IsKind(err, ErrKindNetworkTemporary))
+
+		retry := GetAttrValue(err, "retry")
+		require.Equal(t, "max attempts exceeded", groupValue(retry, "reason").String())
+		require.Equal(t, int64(3), groupValue(retry, "attempts").Int64())
+	})
+
+	t.Run("Retry TLS Handshake Timeout", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return &url.Error{Op: "Get", URL: "https://example.com", Err: tlsHandshakeTimeoutErr{}}
+			}
+			return nil
+		}, WithRetryPolicy(ErrKindNetworkTemporary, fastPolicy))
+		require.NoError(t, err)
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("Permanent Is Not Retried", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), func(ctx context.Context) error {
+			calls++
+			return New("no such host")
+		}, WithRetryPolicy(ErrKindNetworkTemporary, fastPolicy))
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+		require.True(t, IsKind(err, ErrKindNetworkPermanent))
+		require.Equal(t, "non-retryable error kind", groupValue(GetAttrValue(err, "retry"), "reason").String())
+	})
+
+	t.Run("Context Cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := Retry(ctx, func(ctx context.Context) error {
+			calls++
+			cancel()
+			return New("i/o timeout").SetKind(ErrKindNetworkTemporary)
+		}, WithRetryPolicy(ErrKindNetworkTemporary, RetryPolicy{MaxAttempts: 5, InitialDelay: time.Minute}))
+		require.Error(t, err)
+		require.Equal(t, 1, calls)
+		require.Equal(t, "context done", groupValue(GetAttrValue(err, "retry"), "reason").String())
+	})
+
+	t.Run("Backoff Delay", func(t *testing.T) {
+		p := RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond, Multiplier: 2}
+		require.Equal(t, 100*time.Millisecond, p.Delay(1))
+		require.Equal(t, 200*time.Millisecond, p.Delay(2))
+		require.Equal(t, 300*time.Millisecond, p.Delay(3))
+
+		p.Jitter = 0.5
+		for i := 0; i < 10; i++ {
+			d := p.Delay(1)
+			require.True(t, d > 50*time.Millisecond && d <= 100*time.Millisecond)
+		}
+
+		uncapped := RetryPolicy{InitialDelay: time.Second, Multiplier: 2}
+		require.Equal(t, time.Duration(math.MaxInt64), uncapped.Delay(100))
+		require.Equal(t, time.Duration(math.MaxInt64), uncapped.Delay(5000))
+	})
+}
+
+func groupValue(v slog.Value, key string) slog.Value {
+	for _, a := range v.Group() {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return slog.Value{}
+}
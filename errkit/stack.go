@@ -0,0 +1,267 @@
+package errkit
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/utils/env"
+)
+
+var (
+	// MaxStackDepth is the maximum number of frames captured in a stack
+	MaxStackDepth = env.GetEnvOrDefault("ERRKIT_MAX_STACK_DEPTH", 32)
+)
+
+// StackFrame is a single frame of a captured stack
+type StackFrame struct {
+	// Function is the fully qualified function name
+	Function string `json:"function"`
+	// File is the absolute path of the source file
+	File string `json:"file"`
+	// Line is the line number in the source file
+	Line int `json:"line"`
+	// Package is the import path of the package containing the function
+	Package string `json:"package"`
+}
+
+// String returns the frame in `function (file:line)` format
+func (f StackFrame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+// IsStdlib checks if the frame belongs to the go runtime or standard library
+func (f StackFrame) IsStdlib() bool {
+	if f.Package == "" || f.Package == "main" {
+		return false
+	}
+	first, _, _ := strings.Cut(f.Package, "/")
+	return !strings.Contains(first, ".")
+}
+
+// Stack is a list of captured stack frames (innermost first)
+type Stack []StackFrame
+
+// String returns the full multi-line rendering of the stack
+//
+//	main.dial
+//		/app/main.go:42
+//	main.main
+//		/app/main.go:12
+func (s Stack) String() string {
+	var sb strings.Builder
+	for i, f := range s {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(f.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(fmt.Sprintf("%s:%d", f.File, f.Line))
+	}
+	return sb.String()
+}
+
+// Compact returns a single line rendering of the stack suitable for logs
+//
+//	main.dial(main.go:42) <- main.main(main.go:12)
+func (s Stack) Compact() string {
+	parts := make([]string, 0, len(s))
+	for _, f := range s {
+		parts = append(parts, fmt.Sprintf("%s(%s:%d)", shortFunction(f.Function), shortFile(f.File), f.Line))
+	}
+	return strings.Join(parts, " "+DelimArrow+" ")
+}
+
+// StackOption is a filter applied when resolving a stack
+type StackOption func(*stackOptions)
+
+type stackOptions struct {
+	skipStdlib bool
+	skipPkgs   []string
+	maxFrames  int
+}
+
+// WithoutStdlibFrames trims go runtime and standard library frames
+func WithoutStdlibFrames() StackOption {
+	return func(o *stackOptions) {
+		o.skipStdlib = true
+	}
+}
+
+// WithoutPackageFrames trims frames of packages starting with given import path prefixes
+// ex: WithoutPackageFrames("github.com/projectdiscovery/utils")
+func WithoutPackageFrames(prefixes ...string) StackOption {
+	return func(o *stackOptions) {
+		o.skipPkgs = append(o.skipPkgs, prefixes...)
+	}
+}
+
+// WithMaxFrames limits the number of returned frames
+func WithMaxFrames(n int) StackOption {
+	return func(o *stackOptions) {
+		o.maxFrames = n
+	}
+}
+
+// Filter returns a new stack with given filters applied
+func (s Stack) Filter(opts ...StackOption) Stack {
+	o := &stackOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	filtered := Stack{}
+	for _, f := range s {
+		if o.skipStdlib && f.IsStdlib() {
+			continue
+		}
+		if hasAnyPrefix(f.Package, o.skipPkgs) {
+			continue
+		}
+		filtered = append(filtered, f)
+		if o.maxFrames > 0 && len(filtered) >= o.maxFrames {
+			break
+		}
+	}
+	return filtered
+}
+
+// Stack returns the structured stack captured by the error (if any)
+// with given filters applied
+func (e *ErrorX) Stack(opts ...StackOption) Stack {
+	var stack Stack
+	switch {
+	case len(e.pcs) > 0:
+		stack = resolveStack(e.pcs)
+	case len(e.frames) > 0:
+		stack = e.frames
+	case e.source != nil:
+		stack = Stack{newStackFrame(e.source.Function, e.source.File, e.source.Line)}
+	default:
+		return nil
+	}
+	return stack.Filter(opts...)
+}
+
+// GetStack returns the stack captured by given error (if any)
+func GetStack(err error, opts ...StackOption) Stack {
+	if err == nil {
+		return nil
+	}
+	x := &ErrorX{}
+	parseError(x, err)
+	return x.Stack(opts...)
+}
+
+// captureStack captures the program counters of the current stack
+// skipping given number of frames
+func (e *ErrorX) captureStack(skip int) {
+	pcs := make([]uintptr, MaxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	if n == 0 {
+		return
+	}
+	e.pcs = pcs[:n]
+	if e.source == nil {
+		frame, _ := runtime.CallersFrames(e.pcs[:1]).Next()
+		e.source = &slog.Source{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		}
+	}
+}
+
+func resolveStack(pcs []uintptr) Stack {
+	stack := make(Stack, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			stack = append(stack, newStackFrame(frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+func newStackFrame(function, file string, line int) StackFrame {
+	return StackFrame{Function: function, File: file, Line: line, Package: packageName(function)}
+}
+
+// packageName returns the package import path of given fully qualified function
+// ex: github.com/projectdiscovery/utils/errkit.(*ErrorX).Error => github.com/projectdiscovery/utils/errkit
+func packageName(function string) string {
+	lastSlash := strings.LastIndex(function, "/")
+	if lastSlash < 0 {
+		lastSlash = 0
+	}
+	if dot := strings.Index(function[lastSlash:], "."); dot >= 0 {
+		return function[:lastSlash+dot]
+	}
+	return function
+}
+
+// shortFunction returns function name without package path
+func shortFunction(function string) string {
+	if i := strings.LastIndex(function, "/"); i >= 0 {
+		return function[i+1:]
+	}
+	return function
+}
+
+// shortFile returns the file name without directory
+func shortFile(file string) string {
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		return file[i+1:]
+	}
+	return file
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	traceKindsMu sync.RWMutex
+	traceKinds   []ErrKind
+)
+
+// EnableTraceForKinds captures stack only for errors of given kinds (or their children)
+// the stack is captured when the kind is set using SetKind, this keeps
+// errors of other kinds cheap when EnableTrace is disabled
+func EnableTraceForKinds(kinds ...ErrKind) {
+	traceKindsMu.Lock()
+	defer traceKindsMu.Unlock()
+	traceKinds = append(traceKinds, kinds...)
+}
+
+// DisableTraceForKinds removes all kinds configured using EnableTraceForKinds
+func DisableTraceForKinds() {
+	traceKindsMu.Lock()
+	defer traceKindsMu.Unlock()
+	traceKinds = nil
+}
+
+// isTraceEnabledForKind checks if stack should be captured for given kind
+func isTraceEnabledForKind(kind ErrKind) bool {
+	if kind == nil {
+		return false
+	}
+	traceKindsMu.RLock()
+	defer traceKindsMu.RUnlock()
+	for _, k := range traceKinds {
+		if k.Is(kind) || k.IsParent(kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package errkit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStack(t *testing.T) {
+	EnableTrace = true
+	defer func() { EnableTrace = false }()
+
+	x := New("port closed or filtered")
+	stack := x.Stack()
+	require.NotEmpty(t, stack)
+	require.Equal(t, "github.com/projectdiscovery/utils/errkit.TestStack", stack[0].Function)
+	require.Equal(t, "github.com/projectdiscovery/utils/errkit", stack[0].Package)
+	require.True(t, strings.HasSuffix(stack[0].File, "stack_test.go"))
+
+	t.Run("Filters", func(t *testing.T) {
+		hasStdlib := false
+		for _, f := range stack {
+			if f.IsStdlib() {
+				hasStdlib = true
+			}
+		}
+		require.True(t, hasStdlib, "expected testing/runtime frames in unfiltered stack")
+
+		for _, f := range x.Stack(WithoutStdlibFrames()) {
+			require.False(t, f.IsStdlib(), "unexpected stdlib frame %v", f)
+		}
+		require.Empty(t, x.Stack(WithoutStdlibFrames(), WithoutPackageFrames("github.com/projectdiscovery/utils")))
+		require.Len(t, x.Stack(WithMaxFrames(1)), 1)
+	})
+
+	t.Run("Rendering", func(t *testing.T) {
+		compact := x.Stack(WithoutStdlibFrames()).Compact()
+		require.NotContains(t, compact, "\n")
+		require.True(t, strings.HasPrefix(compact, "errkit.TestStack(stack_test.go:"))
+		require.Contains(t, x.Stack().String(), "\n\t")
+	})
+
+	t.Run("Preserved While Wrapping", func(t *testing.T) {
+		wrapped := Wrap(x, "wrapped")
+		require.Equal(t, stack, GetStack(wrapped))
+	})
+
+	t.Run("Json Round Trip", func(t *testing.T) {
+		data, err := json.Marshal(x)
+		require.NoError(t, err)
+		restored := &ErrorX{}
+		require.NoError(t, json.Unmarshal(data, restored))
+		require.Equal(t, stack, restored.Stack())
+	})
+}
+
+func TestTraceForKinds(t *testing.T) {
+	EnableTraceForKinds(ErrKindNetworkPermanent)
+	defer DisableTraceForKinds()
+
+	// child kinds also capture stack
+	traced := New("lookup example.invalid: no such host").SetKind(ErrKindDNSNXDomain)
+	require.NotEmpty(t, traced.Stack())
+	require.Equal(t, "github.com/projectdiscovery/utils/errkit.TestTraceForKinds", traced.Stack()[0].Function)
+
+	untraced := New("i/o timeout").SetKind(ErrKindNetworkTemporary)
+	require.Empty(t, untraced.Stack())
+}
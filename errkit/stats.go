@@ -0,0 +1,120 @@
+package errkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KindCount is the number of errors recorded for a kind
+type KindCount struct {
+	// Kind is the error kind
+	Kind ErrKind
+	// Count is the number of errors classified exactly as this kind
+	Count int
+	// Total is the number of errors classified as this kind or any of its children
+	Total int
+}
+
+// KindStats aggregates error occurrences per error kind
+// counts of child kinds are also rolled up into their parents
+// it is safe for concurrent use
+type KindStats struct {
+	mu     sync.Mutex
+	kinds  map[string]ErrKind
+	counts map[string]int
+	totals map[string]int
+	defs   []ErrKind
+}
+
+// NewKindStats creates a new kind aggregator, extra kinds
+// are used while classifying errors without a kind
+func NewKindStats(defs ...ErrKind) *KindStats {
+	return &KindStats{
+		kinds:  map[string]ErrKind{},
+		counts: map[string]int{},
+		totals: map[string]int{},
+		defs:   defs,
+	}
+}
+
+// Record classifies given error and records its occurrence
+// it returns the kind the error was recorded under
+func (s *KindStats) Record(err error) ErrKind {
+	if err == nil {
+		return nil
+	}
+	kind := GetErrorKind(err, s.defs...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kinds[kind.String()] = kind
+	s.counts[kind.String()]++
+	s.totals[kind.String()]++
+	for p := ParentKind(kind); p != nil; p = ParentKind(p) {
+		s.kinds[p.String()] = p
+		s.totals[p.String()]++
+	}
+	return kind
+}
+
+// Count returns the number of errors recorded for given kind
+// including errors recorded for its children
+func (s *KindStats) Count(kind ErrKind) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totals[kind.String()]
+}
+
+// Top returns the n most frequent kinds ordered by the number of errors
+// classified exactly as that kind, if n <= 0 all kinds are returned
+func (s *KindStats) Top(n int) []KindCount {
+	s.mu.Lock()
+	results := make([]KindCount, 0, len(s.kinds))
+	for id, kind := range s.kinds {
+		if s.counts[id] == 0 {
+			continue
+		}
+		results = append(results, KindCount{Kind: kind, Count: s.counts[id], Total: s.totals[id]})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Kind.String() < results[j].Kind.String()
+	})
+	if n > 0 && len(results) > n {
+		results = results[:n]
+	}
+	return results
+}
+
+// Reset clears all recorded statistics
+func (s *KindStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.kinds = map[string]ErrKind{}
+	s.counts = map[string]int{}
+	s.totals = map[string]int{}
+}
+
+// Summary returns a human readable summary of the n most frequent failure causes
+//
+//	top failure causes:
+//	 - permanent network error (network-permanent-error): 12
+//	 - deadline error (deadline-error): 3
+func (s *KindStats) Summary(n int) string {
+	top := s.Top(n)
+	if len(top) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("top failure causes:")
+	for _, kc := range top {
+		sb.WriteString(fmt.Sprintf("\n - %s (%s): %d", kc.Kind.Description(), kc.Kind.String(), kc.Count))
+	}
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package errorutil
+
+import (
+	"log/slog"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+var _ errkit.ConvertibleError = &enrichedError{}
+
+// LegacyLevelAttr is the attribute key of the legacy error level in converted
+// errors, it avoids colliding with the level key of slog records
+const LegacyLevelAttr = "legacy_level"
+
+// ToErrorX converts the error into errkit.ErrorX, original wrapped errors are
+// preserved for errors.Is/As, tags and level are added as attributes
+// and the callback (if any) fires once on the first conversion
+func (e *enrichedError) ToErrorX() *errkit.ErrorX {
+	return e.toErrorX(true)
+}
+
+// toErrorX converts the error into errkit.ErrorX and optionally fires
+// the callback, predicates (ex: IsAny) convert without firing it
+func (e *enrichedError) toErrorX(fireCallback bool) *errkit.ErrorX {
+	errs := make([]error, 0, len(e.wrappedErrs)+1)
+	for _, err := range e.wrappedErrs {
+		if legacy, ok := err.(*enrichedError); ok {
+			err = legacy.toErrorX(fireCallback)
+		}
+		errs = append(errs, err)
+	}
+	if e.errorX != nil {
+		errs = append(errs, e.errorX)
+	}
+	if len(errs) == 0 {
+		errs = append(errs, errkit.New("error"))
+	}
+	attrs := []slog.Attr{slog.String(LegacyLevelAttr, e.level.String())}
+	if len(e.tags) > 0 {
+		attrs = append(attrs, slog.Any("tags", append([]string{}, e.tags...)))
+	}
+	// attrs are added to the first error before appending the others so that
+	// the result does not share the record of a wrapped error (ex: sentinels)
+	errs[0] = errkit.WithAttr(errs[0], attrs...)
+	x := errkit.FromError(errkit.Append(errs...))
+	if fireCallback && e.callback != nil {
+		e.callbackOnce.Do(func() {
+			x.OnError(func(msg string) {
+				e.callback(e.level, msg, e.tags...)
+			})
+		})
+	}
+	return x
+}
+
+// asErrorX converts legacy errors into errkit.ErrorX without firing
+// their callback, other errors are returned as is
+func asErrorX(err error) error {
+	if legacy, ok := err.(*enrichedError); ok {
+		return legacy.toErrorX(false)
+	}
+	return err
+}
+
+// ToErrorX converts given error into errkit.ErrorX
+// legacy errors are converted losslessly (see errkit.ConvertibleError)
+func ToErrorX(err error) *errkit.ErrorX {
+	return errkit.FromError(err)
+}
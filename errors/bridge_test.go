@@ -0,0 +1,131 @@
+package errorutil_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/projectdiscovery/utils/errkit"
+	errorutil "github.com/projectdiscovery/utils/errors"
+)
+
+func TestToErrorX(t *testing.T) {
+	var ErrTest = errors.New("test error")
+	var callbackMsgs []string
+
+	legacy := errorutil.NewWithErr(ErrTest).
+		WithTag("http", "dial").
+		WithLevel(errorutil.Fatal).
+		WithCallback(func(level errorutil.ErrorLevel, err string, tags ...string) {
+			if level != errorutil.Fatal || len(tags) != 2 {
+				t.Errorf("unexpected callback arguments: %v %v", level, tags)
+			}
+			callbackMsgs = append(callbackMsgs, err)
+		})
+
+	x := errkit.FromError(legacy)
+	if x == nil {
+		t.Fatal("expected errkit error")
+	}
+	if got := errkit.GetAttrValue(x, errorutil.LegacyLevelAttr).String(); got != "FATAL" {
+		t.Errorf("expected level attr FATAL, got %v", got)
+	}
+	if tags, ok := errkit.GetAttrValue(x, "tags").Any().([]string); !ok || len(tags) != 2 || tags[0] != "http" {
+		t.Errorf("expected tags attr, got %v", errkit.GetAttrValue(x, "tags"))
+	}
+	if !errors.Is(x, ErrTest) {
+		t.Errorf("expected converted error to match ErrTest")
+	}
+
+	// callback fires once on conversion, not on every wrap or Error() call
+	wrapped := errkit.Wrap(legacy, "could not connect")
+	_ = wrapped.Error()
+	_ = errkit.FromError(wrapped).Error()
+	if len(callbackMsgs) != 1 {
+		t.Errorf("expected callback to be executed once, got %v", len(callbackMsgs))
+	}
+}
+
+func TestIsAnyInterop(t *testing.T) {
+	var ErrTest = errors.New("test error")
+
+	x := errkit.Wrap(ErrTest, "errkit wrapped")
+	legacy := errorutil.NewWithErr(x).WithTag("legacy")
+
+	if !errorutil.IsAny(legacy, ErrTest) {
+		t.Errorf("expected legacy error to match ErrTest")
+	}
+	if !errorutil.IsAny(x, legacy) {
+		t.Errorf("expected errkit error to match legacy error")
+	}
+	if !errkit.Is(errkit.FromError(legacy), ErrTest) {
+		t.Errorf("expected errkit.Is to match ErrTest")
+	}
+	if errorutil.IsAny(legacy, errors.New("another error")) {
+		t.Errorf("expected errors to not match")
+	}
+}
+
+func TestIsTimeoutInterop(t *testing.T) {
+	legacy := errorutil.NewWithErr(context.DeadlineExceeded).Msgf("request failed")
+	if !errorutil.IsTimeout(legacy) {
+		t.Errorf("expected legacy error to be timeout")
+	}
+	x := errkit.Wrap(fmt.Errorf("dial: %w", context.DeadlineExceeded), "request failed")
+	if !errorutil.IsTimeout(x) {
+		t.Errorf("expected errkit error to be timeout")
+	}
+	if errorutil.IsTimeout(errkit.New("connection refused")) {
+		t.Errorf("expected error to not be timeout")
+	}
+}
+
+func TestPredicatesDoNotFireCallback(t *testing.T) {
+	calls := 0
+	legacy := errorutil.NewWithErr(context.DeadlineExceeded).WithCallback(func(level errorutil.ErrorLevel, err string, tags ...string) {
+		calls++
+	})
+	if !errorutil.IsTimeout(legacy) {
+		t.Errorf("expected legacy error to be timeout")
+	}
+	if !errorutil.IsAny(errkit.Wrap(context.DeadlineExceeded, "wrapped"), legacy) {
+		t.Errorf("expected errkit error to match legacy error")
+	}
+	if errorutil.IsAny(legacy, errors.New("another error")) {
+		t.Errorf("expected errors to not match")
+	}
+	if calls != 0 {
+		t.Errorf("expected predicates to not execute callback, got %v calls", calls)
+	}
+}
+
+func TestToErrorXDoesNotMutateWrapped(t *testing.T) {
+	ErrSentinel := errkit.New("sentinel error")
+	for i := 0; i < 2; i++ {
+		legacy := errorutil.NewWithErr(ErrSentinel).WithTag("legacy")
+		_ = errkit.FromError(legacy.Wrap(errkit.New("other", "index", i)))
+	}
+	if attrs := errkit.GetAttr(ErrSentinel); len(attrs) != 0 {
+		t.Errorf("expected sentinel to have no attributes, got %v", attrs)
+	}
+}
+
+// sliceError is an error whose dynamic type is not comparable
+type sliceError struct {
+	parts []string
+}
+
+func (e sliceError) Error() string {
+	return fmt.Sprint(e.parts)
+}
+
+func TestIsAnyUncomparable(t *testing.T) {
+	err := sliceError{parts: []string{"a", "b"}}
+	if !errorutil.IsAny(err, sliceError{parts: []string{"a", "b"}}) {
+		t.Errorf("expected errors with same message to match")
+	}
+	if errorutil.IsAny(err, sliceError{parts: []string{"c"}}) {
+		t.Errorf("expected errors to not match")
+	}
+}
@@ -7,6 +7,7 @@ package errorutil
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/projectdiscovery/utils/errkit"
 )
@@ -26,6 +27,8 @@ type enrichedError struct {
 	tags        []string
 	callback    ErrCallback
 	wrappedErrs []error // Keep original errors for compatibility
+	// callbackOnce ensures the callback fires once when converted to errkit
+	callbackOnce sync.Once
 }
 
 // WithTag assignes tag to Error
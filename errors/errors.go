@@ -11,6 +11,8 @@ import (
 	"net"
 	"os"
 	"strings"
+
+	"github.com/projectdiscovery/utils/errkit"
 )
 
 // IsAny checks if err is not nil and matches any one of errxx errors
@@ -22,27 +24,37 @@ func IsAny(err error, errxx ...error) bool {
 		return false
 	}
 
+	// legacy errors are compared in their errkit form so that matching
+	// does not convert them (and fire their callback) on every call
+	errX := asErrorX(err)
 	for _, v := range errxx {
 		if v == nil {
 			continue
 		}
+		vX := asErrorX(v)
 
 		// Use stdlib errors.Is for proper err chain traversal
 		// NOTE(dwisiswant0): Check both directions since either error could
 		// wrap the other
-		if errors.Is(err, v) || errors.Is(v, err) {
+		if errors.Is(errX, vX) || errors.Is(vX, errX) {
 			return true
 		}
 
 		// also check enriched error equality (backward-compatible)
 		if enrichedErr, ok := err.(Error); ok {
-			if enrichedErr.Equal(v) {
+			if enrichedErr.Equal(vX) {
 				return true
 			}
 		}
 
+		// errkit errors (and legacy errors converted to errkit) are
+		// compared using their parsed error chains
+		if errkit.FromError(errX).Is(vX) || errkit.FromError(vX).Is(errX) {
+			return true
+		}
+
 		// fallback to str cmp for non-enriched errors
-		if strings.EqualFold(err.Error(), fmt.Sprint(v)) {
+		if strings.EqualFold(errX.Error(), fmt.Sprint(vX)) {
 			return true
 		}
 	}
@@ -77,6 +89,10 @@ func WrapwithNil(err error, errx ...error) Error {
 //
 // Deprecated: Use standard library errors.Is with context.DeadlineExceeded instead.
 func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
 	var net net.Error
-	return (errors.As(err, &net) && net.Timeout()) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+	return (errors.As(err, &net) && net.Timeout()) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		errkit.IsNetworkTemporaryErr(asErrorX(err))
 }
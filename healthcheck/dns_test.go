@@ -3,7 +3,9 @@ package healthcheck
 import (
 	"testing"
 
+	"github.com/projectdiscovery/utils/dns/dnstest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDnsResolve(t *testing.T) {
@@ -26,3 +28,24 @@ func TestDnsResolve(t *testing.T) {
 		assert.Error(t, info.Error)
 	})
 }
+
+func TestDnsResolveLocalServer(t *testing.T) {
+	srv, err := dnstest.NewServer(&dnstest.Zone{
+		Origin:  "example.com",
+		Records: []dnstest.Record{{Name: "www", Type: "A", Value: "192.0.2.1"}},
+	})
+	require.NoError(t, err)
+	defer func() {
+		_ = srv.Close()
+	}()
+
+	info := DnsResolve("www.example.com", srv.Addr)
+	require.NoError(t, info.Error)
+	require.True(t, info.Successful)
+	require.Len(t, info.IPAddresses, 1)
+	require.Equal(t, "192.0.2.1", info.IPAddresses[0].IP.String())
+
+	info = DnsResolve("missing.example.com", srv.Addr)
+	require.Error(t, info.Error)
+	require.False(t, info.Successful)
+}
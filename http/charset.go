@@ -0,0 +1,260 @@
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	stringsutil "github.com/projectdiscovery/utils/strings"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/encoding/unicode/utf32"
+	"golang.org/x/text/transform"
+)
+
+// charsetSniffSize is the number of body bytes inspected to find
+// the BOM, html meta charset or to detect the encoding
+const charsetSniffSize = 4096
+
+var (
+	// <meta charset="x"> or <meta http-equiv="Content-Type" content="text/html; charset=x">
+	metaCharsetRegex = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.\-]+)`)
+
+	// encodings of types enumerated by stringsutil.EncodingType
+	encodingTypes = map[stringsutil.EncodingType]struct {
+		name     string
+		encoding encoding.Encoding
+	}{
+		stringsutil.UTF16BE:     {"utf-16be", unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM)},
+		stringsutil.UTF16LE:     {"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+		stringsutil.UTF32BE:     {"utf-32be", utf32.UTF32(utf32.BigEndian, utf32.IgnoreBOM)},
+		stringsutil.UTF32LE:     {"utf-32le", utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)},
+		stringsutil.ISO85591:    {"iso-8859-1", charmap.ISO8859_1},
+		stringsutil.ISO88592:    {"iso-8859-2", charmap.ISO8859_2},
+		stringsutil.ISO88595:    {"iso-8859-5", charmap.ISO8859_5},
+		stringsutil.ISO88596:    {"iso-8859-6", charmap.ISO8859_6},
+		stringsutil.ISO88597:    {"iso-8859-7", charmap.ISO8859_7},
+		stringsutil.ISO88598:    {"iso-8859-8", charmap.ISO8859_8},
+		stringsutil.Windows1251: {"windows-1251", charmap.Windows1251},
+		stringsutil.Windows1256: {"windows-1256", charmap.Windows1256},
+		stringsutil.KOI8R:       {"koi8-r", charmap.KOI8R},
+		stringsutil.ShiftJIS:    {"shift_jis", japanese.ShiftJIS},
+		stringsutil.GB18030:     {"gb18030", simplifiedchinese.GB18030},
+		stringsutil.EUCJP:       {"euc-jp", japanese.EUCJP},
+		stringsutil.EUCKR:       {"euc-kr", korean.EUCKR},
+		stringsutil.Big5:        {"big5", traditionalchinese.Big5},
+		stringsutil.ISO2022JP:   {"iso-2022-jp", japanese.ISO2022JP},
+	}
+
+	// byte order marks ordered by length (utf-32le bom starts with utf-16le bom)
+	boms = []struct {
+		bom      []byte
+		name     string
+		encoding encoding.Encoding
+	}{
+		{[]byte{0x00, 0x00, 0xFE, 0xFF}, "utf-32be", utf32.UTF32(utf32.BigEndian, utf32.ExpectBOM)},
+		{[]byte{0xFF, 0xFE, 0x00, 0x00}, "utf-32le", utf32.UTF32(utf32.LittleEndian, utf32.ExpectBOM)},
+		{[]byte{0xEF, 0xBB, 0xBF}, "utf-8", unicode.UTF8BOM},
+		{[]byte{0xFE, 0xFF}, "utf-16be", unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+		{[]byte{0xFF, 0xFE}, "utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)},
+	}
+)
+
+// CharsetSource is the source from which the charset of a body was determined
+type CharsetSource string
+
+const (
+	CharsetSourceNone        CharsetSource = ""
+	CharsetSourceBOM         CharsetSource = "bom"
+	CharsetSourceContentType CharsetSource = "content-type"
+	CharsetSourceMeta        CharsetSource = "meta"
+	CharsetSourceDetected    CharsetSource = "detected"
+)
+
+// Charset is the charset of a response body
+type Charset struct {
+	// Name is the lowercase name of the charset (ex: shift_jis)
+	Name string
+	// Source is where the charset was found
+	Source CharsetSource
+	// encoding is nil for utf-8 and unsupported charsets
+	encoding encoding.Encoding
+}
+
+// NeedsTranscoding returns true if body must be transcoded to utf-8
+func (c Charset) NeedsTranscoding() bool {
+	return c.encoding != nil
+}
+
+// DetectCharset determines the charset of a body using (in order) the BOM,
+// the charset parameter of given Content-Type, html <meta> tags and finally
+// stringsutil.DetectEncodingType for bodies which are not valid utf-8.
+// Bodies of non-textual content types (ex: images) are never sniffed, a BOM
+// is only trusted on bodies with a declared text content type and declared
+// legacy charsets are ignored for bodies which are valid utf-8.
+func DetectCharset(contentType string, body []byte) Charset {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" {
+		// sniffed charset is a guess and only used to skip binary bodies
+		mediaType, _, err = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	if err == nil && !isTextualMediaType(mediaType) {
+		return Charset{}
+	}
+	for _, b := range boms {
+		if bytes.HasPrefix(body, b.bom) {
+			// without a declared text content type a leading BOM may as well be
+			// binary data, content types set by net/http server sniffing
+			// (ex: text/plain; charset=utf-16le) are not considered declared
+			if contentType == "" || contentType == http.DetectContentType(body) {
+				return Charset{}
+			}
+			return Charset{Name: b.name, Source: CharsetSourceBOM, encoding: b.encoding}
+		}
+	}
+	if err == nil && params["charset"] != "" {
+		if cs, ok := lookupCharset(params["charset"], CharsetSourceContentType); ok && isDeclaredCharsetValid(cs, body) {
+			return cs
+		}
+	} else if cs, ok := legacyContentTypeCharset(contentType); ok && isDeclaredCharsetValid(cs, body) {
+		return cs
+	}
+	if matches := metaCharsetRegex.FindSubmatch(body); len(matches) == 2 {
+		if cs, ok := lookupCharset(string(matches[1]), CharsetSourceMeta); ok && isDeclaredCharsetValid(cs, body) {
+			return cs
+		}
+	}
+	if len(body) == 0 || utf8.Valid(body) {
+		return Charset{}
+	}
+	encType, err := stringsutil.DetectEncodingType(body)
+	if err != nil {
+		return Charset{}
+	}
+	enc, ok := encodingTypes[encType]
+	if !ok {
+		return Charset{}
+	}
+	return Charset{Name: enc.name, Source: CharsetSourceDetected, encoding: enc.encoding}
+}
+
+// TranscodeToUTF8 transcodes given body to utf-8 using the charset
+// determined by DetectCharset
+func TranscodeToUTF8(contentType string, body []byte) ([]byte, Charset, error) {
+	cs := DetectCharset(contentType, body)
+	if !cs.NeedsTranscoding() {
+		return body, cs, nil
+	}
+	out, _, err := transform.Bytes(cs.encoding.NewDecoder(), body)
+	if err != nil {
+		return body, cs, err
+	}
+	return out, cs, nil
+}
+
+// wrapCharsetReader wraps given reader with a decoder transcoding it to utf-8
+// using the first charsetSniffSize bytes to determine the charset
+func wrapCharsetReader(r io.Reader, contentType string) (io.Reader, Charset) {
+	br := bufio.NewReaderSize(r, charsetSniffSize)
+	// errors are surfaced by subsequent reads
+	prefix, _ := br.Peek(charsetSniffSize)
+	cs := DetectCharset(contentType, prefix)
+	if !cs.NeedsTranscoding() {
+		return br, cs
+	}
+	return transform.NewReader(br, cs.encoding.NewDecoder()), cs
+}
+
+// isDeclaredCharsetValid returns false if body declared in a legacy charset
+// contains non-ascii utf-8, pages are often served as utf-8 while declaring
+// another charset. Ascii bodies (or prefixes) keep the declared charset since
+// non-ascii bytes may follow. utf-16 and utf-32 are exempt since their ascii
+// subset is valid utf-8 as well
+func isDeclaredCharsetValid(cs Charset, body []byte) bool {
+	if cs.encoding == nil || strings.HasPrefix(cs.Name, "utf-16") || strings.HasPrefix(cs.Name, "utf-32") {
+		return true
+	}
+	body = trimIncompleteRune(body)
+	return isASCII(body) || !utf8.Valid(body)
+}
+
+func isASCII(body []byte) bool {
+	for _, b := range body {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// trimIncompleteRune drops a trailing incomplete utf-8 sequence from body,
+// sniffed prefixes may split a multi-byte rune at the boundary
+func trimIncompleteRune(body []byte) []byte {
+	for i := len(body) - 1; i >= 0 && i >= len(body)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(body[i]) {
+			if !utf8.FullRune(body[i:]) {
+				return body[:i]
+			}
+			break
+		}
+	}
+	return body
+}
+
+// isTextualMediaType returns true if body of given media type is text
+func isTextualMediaType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-javascript",
+		"application/ecmascript", "application/xhtml+xml", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// lookupCharset returns the charset for given label
+func lookupCharset(label string, source CharsetSource) (Charset, bool) {
+	label = strings.ToLower(strings.Trim(strings.TrimSpace(label), `"'`))
+	switch label {
+	case "utf-32", "utf-32be":
+		return Charset{Name: "utf-32be", Source: source, encoding: utf32.UTF32(utf32.BigEndian, utf32.UseBOM)}, true
+	case "utf-32le":
+		return Charset{Name: "utf-32le", Source: source, encoding: utf32.UTF32(utf32.LittleEndian, utf32.IgnoreBOM)}, true
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return Charset{}, false
+	}
+	name, _ := htmlindex.Name(enc)
+	cs := Charset{Name: name, Source: source}
+	// replacement encoding would discard the whole body
+	if name != "utf-8" && name != "replacement" {
+		cs.encoding = enc
+	}
+	return cs, true
+}
+
+// legacyContentTypeCharset handles malformed content-type headers
+// which can't be parsed as media types (ex: text/html;charset=gbk;;)
+func legacyContentTypeCharset(contentType string) (Charset, bool) {
+	switch {
+	case isContentTypeGbk(contentType):
+		return Charset{Name: "gbk", Source: CharsetSourceContentType, encoding: simplifiedchinese.GBK}, true
+	case isContentTypeWindows1251(contentType):
+		return Charset{Name: "windows-1251", Source: CharsetSourceContentType, encoding: charmap.Windows1251}, true
+	}
+	return Charset{}, false
+}
@@ -0,0 +1,108 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func mustEncode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	data, err := enc.NewEncoder().Bytes([]byte(s))
+	require.NoError(t, err)
+	return data
+}
+
+func TestDetectCharset(t *testing.T) {
+	const russian = "Съешь же ещё этих мягких французских булок, да выпей чаю. Широкая электрификация южных губерний даст мощный толчок подъёму сельского хозяйства."
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		charset     string
+		source      CharsetSource
+		expected    string
+	}{
+		{"content-type", "text/html; charset=Shift_JIS", mustEncode(t, japanese.ShiftJIS, "こんにちは世界"), "shift_jis", CharsetSourceContentType, "こんにちは世界"},
+		{"meta charset", "text/html", append([]byte(`<html><head><meta charset="euc-kr"></head><body>`), mustEncode(t, korean.EUCKR, "안녕하세요")...), "euc-kr", CharsetSourceMeta, `<html><head><meta charset="euc-kr"></head><body>안녕하세요`},
+		{"meta http-equiv", "", append([]byte(`<meta http-equiv="Content-Type" content="text/html; charset=koi8-r">`), mustEncode(t, charmap.KOI8R, "привет")...), "koi8-r", CharsetSourceMeta, `<meta http-equiv="Content-Type" content="text/html; charset=koi8-r">привет`},
+		{"bom", "text/plain", mustEncode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "hello"), "utf-16le", CharsetSourceBOM, "hello"},
+		{"detected", "text/plain", mustEncode(t, charmap.KOI8R, russian), "koi8-r", CharsetSourceDetected, russian},
+		{"legacy content-type", "text/html;charset=gbk;;", mustEncode(t, simplifiedchinese.GBK, "你好"), "gbk", CharsetSourceContentType, "你好"},
+		{"utf-8", "text/html", []byte("<meta charset=\"iso-8859-1\">héllo"), "", CharsetSourceNone, "<meta charset=\"iso-8859-1\">héllo"},
+		{"binary", "image/png", []byte{0xff, 0xfe, 0x00, 0x01}, "", CharsetSourceNone, "\xff\xfe\x00\x01"},
+		{"bom without content-type", "", []byte{0xff, 0xfe, 0x00, 0x01}, "", CharsetSourceNone, "\xff\xfe\x00\x01"},
+		{"declared latin1 served as utf-8", "text/html; charset=iso-8859-1", []byte("héllo wörld"), "", CharsetSourceNone, "héllo wörld"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, cs, err := TranscodeToUTF8(tt.contentType, tt.body)
+			require.NoError(t, err)
+			require.Equal(t, tt.charset, cs.Name)
+			require.Equal(t, tt.source, cs.Source)
+			require.Equal(t, tt.expected, string(out))
+		})
+	}
+}
+
+func TestCharsetSniffSplitRune(t *testing.T) {
+	// the sniffed prefix ends in the middle of a two byte rune
+	body := "a" + strings.Repeat("привет", charsetSniffSize/12+1)
+	require.False(t, utf8.RuneStart(body[charsetSniffSize]))
+
+	r, cs := wrapCharsetReader(strings.NewReader(body), "text/html; charset=windows-1251")
+	require.Equal(t, CharsetSourceNone, cs.Source)
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, body, string(out))
+}
+
+func TestCharsetSniffASCIIPrefix(t *testing.T) {
+	// the declared charset is kept when the sniffed prefix is ascii
+	head := "<html><head>" + strings.Repeat("<meta name=\"x\" content=\"y\">", charsetSniffSize/30) + "</head><body>"
+	body := append([]byte(head), mustEncode(t, simplifiedchinese.GBK, "你好世界")...)
+
+	r, cs := wrapCharsetReader(bytes.NewReader(body), "text/html; charset=gbk")
+	require.Equal(t, "gbk", cs.Name)
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, head+"你好世界", string(out))
+}
+
+func TestResponseChainCharset(t *testing.T) {
+	raw := mustEncode(t, japanese.ShiftJIS, "日本語のページ")
+	newResp := func() *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader(raw)),
+			Header:     http.Header{"Content-Type": []string{"text/html; charset=shift_jis"}},
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+		}
+	}
+
+	rc := NewResponseChain(newResp(), -1, WithRawBody())
+	require.NoError(t, rc.Fill())
+	require.Equal(t, "日本語のページ", rc.BodyString())
+	require.Equal(t, raw, rc.RawBodyBytes())
+	require.Equal(t, "shift_jis", rc.Charset().Name)
+	rc.Close()
+
+	// without raw body option the normalized body is returned
+	rc = NewResponseChain(newResp(), -1)
+	require.NoError(t, rc.Fill())
+	require.True(t, strings.HasPrefix(string(rc.RawBodyBytes()), "日本語"))
+	rc.Close()
+}
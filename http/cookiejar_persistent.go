@@ -0,0 +1,548 @@
+package httputil
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/utils/errkit"
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieFormat is the serialization format of cookies
+type CookieFormat int
+
+const (
+	// CookieFormatJSON is the json format used by browser extensions (ex: Cookie-Editor)
+	CookieFormatJSON CookieFormat = iota
+	// CookieFormatNetscape is the Netscape cookies.txt format used by curl/wget
+	CookieFormatNetscape
+)
+
+const (
+	netscapeHeader   = "# Netscape HTTP Cookie File"
+	netscapeHttpOnly = "#HttpOnly_"
+)
+
+var _ http.CookieJar = &PersistentCookieJar{}
+
+// StoredCookie is a cookie stored in the jar along with its scope
+type StoredCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Domain is the domain of the cookie without leading dot
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+	// ExpirationDate is the unix time of expiration (0 for session cookies)
+	ExpirationDate float64 `json:"expirationDate,omitempty"`
+	// HostOnly is true if cookie is only sent to the exact domain
+	HostOnly bool   `json:"hostOnly"`
+	HttpOnly bool   `json:"httpOnly"`
+	Secure   bool   `json:"secure"`
+	Session  bool   `json:"session"`
+	SameSite string `json:"sameSite,omitempty"`
+
+	creation time.Time
+}
+
+// Expires returns the expiration time of the cookie (zero for session cookies)
+func (c *StoredCookie) Expires() time.Time {
+	if c.Session || c.ExpirationDate <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(c.ExpirationDate)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// Cookie returns the cookie as *http.Cookie
+func (c *StoredCookie) Cookie() *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires(),
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+	switch c.SameSite {
+	case "lax":
+		cookie.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cookie.SameSite = http.SameSiteStrictMode
+	case "no_restriction":
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	return cookie
+}
+
+func (c *StoredCookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c *StoredCookie) expired(now time.Time) bool {
+	expires := c.Expires()
+	return !expires.IsZero() && !expires.After(now)
+}
+
+// PersistentCookieJar is a thread-safe cookie jar whose cookies can be
+// enumerated, saved and loaded (Netscape and JSON formats)
+type PersistentCookieJar struct {
+	mu      sync.RWMutex
+	entries map[string]*StoredCookie
+	now     func() time.Time
+}
+
+// NewPersistentCookieJar creates a new empty persistent cookie jar
+func NewPersistentCookieJar() *PersistentCookieJar {
+	return &PersistentCookieJar{entries: map[string]*StoredCookie{}, now: time.Now}
+}
+
+// SetCookies implements http.CookieJar.SetCookies
+func (j *PersistentCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	for _, c := range cookies {
+		entry, ok := newStoredCookie(c, host, u.Path, now)
+		if !ok {
+			continue
+		}
+		if c.MaxAge < 0 || entry.expired(now) {
+			delete(j.entries, entry.key())
+			continue
+		}
+		if old, ok := j.entries[entry.key()]; ok {
+			entry.creation = old.creation
+		}
+		j.entries[entry.key()] = entry
+	}
+}
+
+// Cookies implements http.CookieJar.Cookies
+func (j *PersistentCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	https := u.Scheme == "https" || u.Scheme == "wss"
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	matched := []*StoredCookie{}
+	for key, entry := range j.entries {
+		if entry.expired(now) {
+			delete(j.entries, key)
+			continue
+		}
+		if entry.Secure && !https {
+			continue
+		}
+		if !entry.domainMatch(host) || !pathMatch(entry.Path, path) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	// longer paths first then older cookies first as per RFC 6265
+	sort.Slice(matched, func(i, k int) bool {
+		if len(matched[i].Path) != len(matched[k].Path) {
+			return len(matched[i].Path) > len(matched[k].Path)
+		}
+		return matched[i].creation.Before(matched[k].creation)
+	})
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, entry := range matched {
+		cookies = append(cookies, &http.Cookie{Name: entry.Name, Value: entry.Value})
+	}
+	return cookies
+}
+
+// Entries returns all non-expired cookies stored in the jar
+// sorted by domain, path and name
+func (j *PersistentCookieJar) Entries() []StoredCookie {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	now := j.now()
+	entries := make([]StoredCookie, 0, len(j.entries))
+	for _, entry := range j.entries {
+		if entry.expired(now) {
+			continue
+		}
+		e := *entry
+		// creation time is only used internally for ordering
+		e.creation = time.Time{}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, k int) bool {
+		return entries[i].key() < entries[k].key()
+	})
+	return entries
+}
+
+// All returns all non-expired cookies stored in the jar with their scope
+func (j *PersistentCookieJar) All() []*http.Cookie {
+	entries := j.Entries()
+	cookies := make([]*http.Cookie, 0, len(entries))
+	for i := range entries {
+		cookies = append(cookies, entries[i].Cookie())
+	}
+	return cookies
+}
+
+// Clear removes all cookies from the jar
+func (j *PersistentCookieJar) Clear() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = map[string]*StoredCookie{}
+}
+
+// Add adds given stored cookies to the jar as is
+func (j *PersistentCookieJar) Add(cookies ...StoredCookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := j.now()
+	for _, c := range cookies {
+		c.Domain = strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+		if c.Name == "" || c.Domain == "" {
+			continue
+		}
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		if c.ExpirationDate <= 0 {
+			c.Session = true
+		}
+		if c.expired(now) {
+			continue
+		}
+		c.creation = now
+		entry := c
+		j.entries[entry.key()] = &entry
+	}
+}
+
+// ImportHeaders imports cookies from a raw dump of `Cookie` and `Set-Cookie`
+// headers (one header per line) received from / sent to given url
+//
+//	Cookie: session=abc; theme=dark
+//	Set-Cookie: id=1; Path=/; HttpOnly
+func (j *PersistentCookieJar) ImportHeaders(u *url.URL, raw string) error {
+	cookies := []*http.Cookie{}
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "set-cookie":
+			cookie, err := http.ParseSetCookie(value)
+			if err != nil {
+				return errkit.Wrapf(err, "could not parse set-cookie header %q", value)
+			}
+			cookies = append(cookies, cookie)
+		case "cookie":
+			parsed, err := http.ParseCookie(value)
+			if err != nil {
+				return errkit.Wrapf(err, "could not parse cookie header %q", value)
+			}
+			for _, cookie := range parsed {
+				// request cookies do not carry scope, assume whole host
+				cookie.Path = "/"
+				cookies = append(cookies, cookie)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	j.SetCookies(u, cookies)
+	return nil
+}
+
+// Save writes all cookies to given writer in given format
+func (j *PersistentCookieJar) Save(w io.Writer, format CookieFormat) error {
+	entries := j.Entries()
+	switch format {
+	case CookieFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case CookieFormatNetscape:
+		bw := bufio.NewWriter(w)
+		_, _ = fmt.Fprintln(bw, netscapeHeader)
+		for _, c := range entries {
+			domain := c.Domain
+			includeSubdomains := "FALSE"
+			if !c.HostOnly {
+				domain = "." + domain
+				includeSubdomains = "TRUE"
+			}
+			if c.HttpOnly {
+				domain = netscapeHttpOnly + domain
+			}
+			var expires int64
+			if !c.Session {
+				expires = c.Expires().Unix()
+			}
+			_, _ = fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, includeSubdomains, c.Path, netscapeBool(c.Secure), expires, c.Name, c.Value)
+		}
+		return bw.Flush()
+	default:
+		return errkit.Newf("unsupported cookie format %d", format)
+	}
+}
+
+// Load reads cookies from given reader in given format and adds them to the jar
+func (j *PersistentCookieJar) Load(r io.Reader, format CookieFormat) error {
+	switch format {
+	case CookieFormatJSON:
+		var entries []StoredCookie
+		if err := json.NewDecoder(r).Decode(&entries); err != nil {
+			return errkit.Wrap(err, "could not decode json cookies")
+		}
+		j.Add(entries...)
+		return nil
+	case CookieFormatNetscape:
+		entries := []StoredCookie{}
+		scanner := bufio.NewScanner(r)
+		lineNo := 0
+		for scanner.Scan() {
+			lineNo++
+			line := strings.TrimSpace(scanner.Text())
+			httpOnly := false
+			if strings.HasPrefix(line, netscapeHttpOnly) {
+				httpOnly = true
+				line = strings.TrimPrefix(line, netscapeHttpOnly)
+			}
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.Split(line, "\t")
+			if len(fields) < 6 {
+				return errkit.New("invalid netscape cookie line", "line", lineNo)
+			}
+			if len(fields) == 6 {
+				// cookies without value
+				fields = append(fields, "")
+			}
+			expires, err := strconv.ParseInt(fields[4], 10, 64)
+			if err != nil {
+				return errkit.Wrapf(err, "invalid expiry on line %d", lineNo)
+			}
+			entries = append(entries, StoredCookie{
+				Name:           fields[5],
+				Value:          fields[6],
+				Domain:         fields[0],
+				Path:           fields[2],
+				HostOnly:       !strings.EqualFold(fields[1], "TRUE"),
+				HttpOnly:       httpOnly,
+				Secure:         strings.EqualFold(fields[3], "TRUE"),
+				ExpirationDate: float64(expires),
+				Session:        expires == 0,
+			})
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		j.Add(entries...)
+		return nil
+	default:
+		return errkit.Newf("unsupported cookie format %d", format)
+	}
+}
+
+// SaveFile saves all cookies to given file in given format
+// the file is created with 0600 permissions since cookies are credentials
+func (j *PersistentCookieJar) SaveFile(filename string, format CookieFormat) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := j.Save(f, format); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadFile loads cookies from given file in given format
+func (j *PersistentCookieJar) LoadFile(filename string, format CookieFormat) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return j.Load(f, format)
+}
+
+// PartitionedCookieJar keeps an isolated cookie jar per partition key
+// (ex: scan target) so that concurrent targets do not share sessions
+type PartitionedCookieJar struct {
+	mu   sync.Mutex
+	jars map[string]*PersistentCookieJar
+}
+
+// NewPartitionedCookieJar creates a new partitioned cookie jar
+func NewPartitionedCookieJar() *PartitionedCookieJar {
+	return &PartitionedCookieJar{jars: map[string]*PersistentCookieJar{}}
+}
+
+// Partition returns the cookie jar of given partition creating it if necessary
+func (p *PartitionedCookieJar) Partition(key string) *PersistentCookieJar {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	jar, ok := p.jars[key]
+	if !ok {
+		jar = NewPersistentCookieJar()
+		p.jars[key] = jar
+	}
+	return jar
+}
+
+// Partitions returns all partition keys
+func (p *PartitionedCookieJar) Partitions() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]string, 0, len(p.jars))
+	for key := range p.jars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Delete removes given partition and all its cookies
+func (p *PartitionedCookieJar) Delete(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.jars, key)
+}
+
+// newStoredCookie validates and scopes given cookie received from host
+func newStoredCookie(c *http.Cookie, host, requestPath string, now time.Time) (*StoredCookie, bool) {
+	if c == nil || c.Name == "" {
+		return nil, false
+	}
+	entry := &StoredCookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Path:     c.Path,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		Session:  true,
+		creation: now,
+	}
+	switch c.SameSite {
+	case http.SameSiteLaxMode:
+		entry.SameSite = "lax"
+	case http.SameSiteStrictMode:
+		entry.SameSite = "strict"
+	case http.SameSiteNoneMode:
+		entry.SameSite = "no_restriction"
+	}
+
+	domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+	switch {
+	case domain == "" || domain == host:
+		entry.Domain = host
+		entry.HostOnly = domain == ""
+	case net.ParseIP(host) != nil:
+		// ip addresses only accept host-only cookies
+		return nil, false
+	case !strings.HasSuffix(host, "."+domain):
+		return nil, false
+	default:
+		// reject cookies set for public suffixes (ex: .co.uk)
+		if ps, _ := publicsuffix.PublicSuffix(domain); ps == domain {
+			return nil, false
+		}
+		entry.Domain = domain
+	}
+
+	if entry.Path == "" || !strings.HasPrefix(entry.Path, "/") {
+		entry.Path = defaultCookiePath(requestPath)
+	}
+
+	switch {
+	case c.MaxAge > 0:
+		entry.ExpirationDate = float64(now.Add(time.Duration(c.MaxAge)*time.Second).UnixNano()) / 1e9
+		entry.Session = false
+	case !c.Expires.IsZero():
+		entry.ExpirationDate = float64(c.Expires.UnixNano()) / 1e9
+		entry.Session = false
+	}
+	return entry, true
+}
+
+func (c *StoredCookie) domainMatch(host string) bool {
+	if c.Domain == host {
+		return true
+	}
+	return !c.HostOnly && strings.HasSuffix(host, "."+c.Domain)
+}
+
+// pathMatch implements path-match as per RFC 6265 section 5.1.4
+func pathMatch(cookiePath, requestPath string) bool {
+	if cookiePath == requestPath {
+		return true
+	}
+	if strings.HasPrefix(requestPath, cookiePath) {
+		return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+	}
+	return false
+}
+
+// defaultCookiePath returns the default path as per RFC 6265 section 5.1.4
+func defaultCookiePath(requestPath string) string {
+	if requestPath == "" || requestPath[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(requestPath, "/")
+	if i == 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// canonicalHost strips port and converts host to lowercase
+func canonicalHost(host string) (string, error) {
+	if host == "" {
+		return "", errkit.New("empty host")
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	return strings.ToLower(host), nil
+}
+
+func netscapeBool(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,114 @@
+package httputil
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func cookieNames(cookies []*http.Cookie) []string {
+	names := []string{}
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func TestPersistentCookieJar(t *testing.T) {
+	jar := NewPersistentCookieJar()
+	u, _ := url.Parse("https://www.example.com/app/login")
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "host", Value: "1"},
+		{Name: "domain", Value: "2", Domain: ".example.com", Path: "/"},
+		{Name: "secure", Value: "3", Path: "/", Secure: true, HttpOnly: true},
+		{Name: "expiring", Value: "4", Path: "/", MaxAge: 3600},
+		{Name: "public", Value: "5", Domain: "com"},
+		{Name: "other", Value: "6", Domain: "other.com"},
+	})
+	require.Len(t, jar.Entries(), 4, "public suffix and foreign domain cookies must be rejected")
+
+	t.Run("Matching", func(t *testing.T) {
+		got := jar.Cookies(u)
+		require.ElementsMatch(t, []string{"host", "domain", "secure", "expiring"}, cookieNames(got))
+
+		sub, _ := url.Parse("http://api.example.com/")
+		require.Equal(t, []string{"domain"}, cookieNames(jar.Cookies(sub)))
+
+		other, _ := url.Parse("https://www.example.com/other")
+		require.NotContains(t, cookieNames(jar.Cookies(other)), "host", "host cookie is scoped to /app")
+	})
+
+	t.Run("Deletion", func(t *testing.T) {
+		jar.SetCookies(u, []*http.Cookie{{Name: "expiring", Path: "/", MaxAge: -1}})
+		require.NotContains(t, cookieNames(jar.All()), "expiring")
+	})
+
+	for _, format := range []CookieFormat{CookieFormatNetscape, CookieFormatJSON} {
+		var buf bytes.Buffer
+		require.NoError(t, jar.Save(&buf, format))
+
+		restored := NewPersistentCookieJar()
+		require.NoError(t, restored.Load(&buf, format))
+		require.Equal(t, cookieNames(jar.All()), cookieNames(restored.All()))
+		require.ElementsMatch(t, cookieNames(jar.Cookies(u)), cookieNames(restored.Cookies(u)))
+	}
+}
+
+func TestPersistentCookieJarNetscape(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	data := strings.Join([]string{
+		netscapeHeader,
+		"",
+		".example.com\tTRUE\t/\tFALSE\t" + strconv.FormatInt(expires, 10) + "\tsid\tabc",
+		"#HttpOnly_www.example.com\tFALSE\t/\tTRUE\t0\ttoken\txyz",
+		"old.example.com\tFALSE\t/\tFALSE\t1\texpired\tv",
+	}, "\n")
+
+	jar := NewPersistentCookieJar()
+	require.NoError(t, jar.Load(strings.NewReader(data), CookieFormatNetscape))
+	entries := jar.Entries()
+	require.Len(t, entries, 2)
+	require.Equal(t, "sid", entries[0].Name)
+	require.False(t, entries[0].HostOnly)
+	require.Equal(t, "token", entries[1].Name)
+	require.True(t, entries[1].HttpOnly)
+	require.True(t, entries[1].Session)
+
+	filename := filepath.Join(t.TempDir(), "cookies.txt")
+	require.NoError(t, jar.SaveFile(filename, CookieFormatNetscape))
+	restored := NewPersistentCookieJar()
+	require.NoError(t, restored.LoadFile(filename, CookieFormatNetscape))
+	require.Equal(t, entries, restored.Entries())
+
+	require.Error(t, jar.Load(strings.NewReader("example.com\tTRUE\t/"), CookieFormatNetscape))
+}
+
+func TestPersistentCookieJarImportHeaders(t *testing.T) {
+	jar := NewPersistentCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	dump := "Cookie: a=1; b=2\nSet-Cookie: c=3; Path=/; HttpOnly\nContent-Type: text/html\n"
+	require.NoError(t, jar.ImportHeaders(u, dump))
+	require.ElementsMatch(t, []string{"a", "b", "c"}, cookieNames(jar.Cookies(u)))
+}
+
+func TestPartitionedCookieJar(t *testing.T) {
+	jars := NewPartitionedCookieJar()
+	u, _ := url.Parse("https://example.com/")
+	jars.Partition("target-a").SetCookies(u, []*http.Cookie{{Name: "session", Value: "a"}})
+	jars.Partition("target-b").SetCookies(u, []*http.Cookie{{Name: "session", Value: "b"}})
+
+	require.Equal(t, []string{"target-a", "target-b"}, jars.Partitions())
+	require.Equal(t, "a", jars.Partition("target-a").Cookies(u)[0].Value)
+	require.Equal(t, "b", jars.Partition("target-b").Cookies(u)[0].Value)
+
+	jars.Delete("target-a")
+	require.Empty(t, jars.Partition("target-a").Cookies(u))
+}
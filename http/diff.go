@@ -0,0 +1,382 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DiffComponent names
+const (
+	DiffStatus  = "status"
+	DiffHeaders = "headers"
+	DiffLines   = "lines"
+	DiffWords   = "words"
+	DiffTags    = "tags"
+	DiffSimhash = "simhash"
+	DiffTiming  = "timing"
+)
+
+var (
+	// DefaultDiffWeights are the weights of each component in the total score
+	DefaultDiffWeights = map[string]float64{
+		DiffStatus:  0.30,
+		DiffHeaders: 0.10,
+		DiffLines:   0.15,
+		DiffWords:   0.15,
+		DiffTags:    0.15,
+		DiffSimhash: 0.10,
+		DiffTiming:  0.05,
+	}
+
+	// DefaultVolatileHeaders are headers whose values change between identical
+	// requests, only their presence is compared. Set-Cookie is compared by
+	// cookie names.
+	DefaultVolatileHeaders = []string{
+		"Age", "Cf-Ray", "Content-Length", "Date", "Etag", "Expires", "Last-Modified",
+		"Nel", "Report-To", "Server-Timing", "Set-Cookie", "Traceparent", "X-Amz-Cf-Id",
+		"X-Amz-Request-Id", "X-Cache", "X-Request-Id", "X-Runtime", "X-Served-By", "X-Timer", "X-Trace-Id",
+	}
+
+	// maximum number of html tags compared (lcs is quadratic)
+	maxDiffTags = 2000
+	// maximum number of samples kept per component
+	maxDiffSamples = 5
+
+	tagRegex = regexp.MustCompile(`<\s*(/?[a-zA-Z][a-zA-Z0-9-]*)`)
+)
+
+// DiffComponent is the difference of a single aspect of two responses
+type DiffComponent struct {
+	Name string `json:"name"`
+	// Score is the difference from 0 (identical) to 1 (completely different)
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+	// Details explain the difference (ex: "status 200 => 404")
+	Details []string `json:"details,omitempty"`
+	// Skipped is true if the component does not apply to the responses
+	// (ex: tags of non html bodies), it is excluded from the score
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// DiffReport is the result of comparing two responses
+type DiffReport struct {
+	// Score is the weighted difference from 0 (identical) to 1 (completely different)
+	Score      float64         `json:"score"`
+	Components []DiffComponent `json:"components"`
+}
+
+// Different returns true if the score of the report is greater than threshold
+func (d *DiffReport) Different(threshold float64) bool {
+	return d.Score > threshold
+}
+
+// Component returns the component with given name
+func (d *DiffReport) Component(name string) (DiffComponent, bool) {
+	for _, c := range d.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return DiffComponent{}, false
+}
+
+// String returns a human readable explanation of the differences
+func (d *DiffReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "score: %.3f\n", d.Score)
+	for _, c := range d.Components {
+		if c.Score == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %.3f (weight %.2f)\n", c.Name, c.Score, c.Weight)
+		for _, detail := range c.Details {
+			fmt.Fprintf(&sb, "  %s\n", detail)
+		}
+	}
+	return sb.String()
+}
+
+// DiffOption is a configuration option for Diff
+type DiffOption func(*differ)
+
+// WithDiffWeights overrides the weights of given components (see DefaultDiffWeights)
+func WithDiffWeights(weights map[string]float64) DiffOption {
+	return func(d *differ) {
+		for k, v := range weights {
+			d.weights[k] = v
+		}
+	}
+}
+
+// WithVolatileHeaders adds headers whose values are ignored
+func WithVolatileHeaders(headers ...string) DiffOption {
+	return func(d *differ) {
+		for _, h := range headers {
+			d.volatile[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+}
+
+// WithDiffTimings sets the durations of both responses. Durations differing
+// by less than tolerance are considered identical.
+func WithDiffTimings(a, b, tolerance time.Duration) DiffOption {
+	return func(d *differ) {
+		d.durationA, d.durationB, d.tolerance = a, b, tolerance
+	}
+}
+
+type differ struct {
+	weights              map[string]float64
+	volatile             map[string]bool
+	durationA, durationB time.Duration
+	tolerance            time.Duration
+}
+
+// Diff compares the current responses of two filled chains (ex: baseline and
+// fuzzed request) and returns a scored report of their differences. Bodies are
+// compared after normalizing volatile tokens (see NormalizeBody).
+func Diff(a, b *ResponseChain, opts ...DiffOption) *DiffReport {
+	d := &differ{weights: map[string]float64{}, volatile: map[string]bool{}}
+	for k, v := range DefaultDiffWeights {
+		d.weights[k] = v
+	}
+	for _, h := range DefaultVolatileHeaders {
+		d.volatile[h] = true
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	respA, respB := a.Response(), b.Response()
+	bodyA, bodyB := a.BodyString(), b.BodyString()
+	components := []DiffComponent{
+		diffStatus(respA, respB),
+		d.headers(respA, respB),
+		diffLines(bodyA, bodyB),
+		diffWords(bodyA, bodyB),
+		diffTags(bodyA, bodyB),
+		diffSimhash(bodyA, bodyB),
+		d.timing(),
+	}
+
+	report := &DiffReport{}
+	var total float64
+	for _, c := range components {
+		c.Weight = d.weights[c.Name]
+		report.Components = append(report.Components, c)
+		if c.Skipped {
+			continue
+		}
+		report.Score += c.Score * c.Weight
+		total += c.Weight
+	}
+	if total > 0 {
+		report.Score /= total
+	}
+	return report
+}
+
+func diffStatus(a, b *http.Response) DiffComponent {
+	c := DiffComponent{Name: DiffStatus}
+	statusA, statusB := 0, 0
+	if a != nil {
+		statusA = a.StatusCode
+	}
+	if b != nil {
+		statusB = b.StatusCode
+	}
+	if statusA != statusB {
+		c.Score = 1
+		c.Details = append(c.Details, fmt.Sprintf("status %d => %d", statusA, statusB))
+	}
+	return c
+}
+
+// headers compares the header sets using the jaccard distance
+func (d *differ) headers(a, b *http.Response) DiffComponent {
+	c := DiffComponent{Name: DiffHeaders}
+	setA, setB := d.headerSet(a), d.headerSet(b)
+	removed, added := setDifference(setA, setB), setDifference(setB, setA)
+	union := len(setA) + len(added)
+	if union == 0 {
+		return c
+	}
+	c.Score = float64(len(removed)+len(added)) / float64(union)
+	for _, h := range removed {
+		c.Details = append(c.Details, "- "+h)
+	}
+	for _, h := range added {
+		c.Details = append(c.Details, "+ "+h)
+	}
+	return c
+}
+
+// headerSet returns "name: value" entries of the response headers (only the
+// name for volatile headers and "Set-Cookie: name" for cookies)
+func (d *differ) headerSet(resp *http.Response) map[string]struct{} {
+	set := map[string]struct{}{}
+	if resp == nil {
+		return set
+	}
+	for name, values := range resp.Header {
+		switch {
+		case name == "Set-Cookie":
+			for _, v := range values {
+				cookieName, _, _ := strings.Cut(v, "=")
+				set[name+": "+strings.TrimSpace(cookieName)] = struct{}{}
+			}
+		case d.volatile[name]:
+			set[name] = struct{}{}
+		default:
+			for _, v := range values {
+				set[name+": "+v] = struct{}{}
+			}
+		}
+	}
+	return set
+}
+
+func (d *differ) timing() DiffComponent {
+	c := DiffComponent{Name: DiffTiming}
+	if d.durationA <= 0 || d.durationB <= 0 {
+		c.Skipped = true
+		return c
+	}
+	delta := d.durationA - d.durationB
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= d.tolerance {
+		return c
+	}
+	c.Score = float64(delta) / float64(max(d.durationA, d.durationB))
+	c.Details = append(c.Details, fmt.Sprintf("duration %s => %s", d.durationA, d.durationB))
+	return c
+}
+
+// diffLines compares the normalized non-empty lines of both bodies as multisets
+func diffLines(a, b string) DiffComponent {
+	return diffTokens(DiffLines, normalizedLines(a), normalizedLines(b))
+}
+
+// diffWords compares the words of both normalized bodies as multisets
+func diffWords(a, b string) DiffComponent {
+	return diffTokens(DiffWords, strings.Fields(NormalizeBody(a)), strings.Fields(NormalizeBody(b)))
+}
+
+func diffTokens(name string, a, b []string) DiffComponent {
+	c := DiffComponent{Name: name}
+	if len(a)+len(b) == 0 {
+		return c
+	}
+	removed, added := multisetDifference(a, b), multisetDifference(b, a)
+	c.Score = float64(len(removed)+len(added)) / float64(len(a)+len(b))
+	if c.Score == 0 {
+		return c
+	}
+	c.Details = append(c.Details, fmt.Sprintf("%d %s removed, %d added", len(removed), name, len(added)))
+	for _, token := range removed[:min(len(removed), maxDiffSamples)] {
+		c.Details = append(c.Details, "- "+token)
+	}
+	for _, token := range added[:min(len(added), maxDiffSamples)] {
+		c.Details = append(c.Details, "+ "+token)
+	}
+	return c
+}
+
+// diffTags compares the sequences of html tags using their longest common subsequence
+func diffTags(a, b string) DiffComponent {
+	c := DiffComponent{Name: DiffTags}
+	tagsA, tagsB := htmlTags(a), htmlTags(b)
+	if len(tagsA)+len(tagsB) == 0 {
+		c.Skipped = true
+		return c
+	}
+	common := lcsLength(tagsA, tagsB)
+	c.Score = 1 - 2*float64(common)/float64(len(tagsA)+len(tagsB))
+	if c.Score > 0 {
+		c.Details = append(c.Details, fmt.Sprintf("%d tags => %d tags, %d in common", len(tagsA), len(tagsB), common))
+	}
+	return c
+}
+
+// diffSimhash compares the simhashes of the normalized bodies, unrelated
+// texts have a distance of ~32 bits which is scored as completely different
+func diffSimhash(a, b string) DiffComponent {
+	c := DiffComponent{Name: DiffSimhash}
+	distance := SimhashDistance(Simhash(NormalizeBody(a)), Simhash(NormalizeBody(b)))
+	if distance > 0 {
+		c.Score = min(1, float64(distance)/32)
+		c.Details = append(c.Details, fmt.Sprintf("simhash distance %d", distance))
+	}
+	return c
+}
+
+func normalizedLines(body string) []string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = NormalizeBody(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// htmlTags returns the sequence of (lowercase) tag names of given html
+func htmlTags(body string) []string {
+	matches := tagRegex.FindAllStringSubmatch(body, maxDiffTags)
+	tags := make([]string, 0, len(matches))
+	for _, m := range matches {
+		tags = append(tags, strings.ToLower(m[1]))
+	}
+	return tags
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// multisetDifference returns the items of a not in b (counting duplicates)
+func multisetDifference(a, b []string) []string {
+	counts := make(map[string]int, len(b))
+	for _, item := range b {
+		counts[item]++
+	}
+	var diff []string
+	for _, item := range a {
+		if counts[item] > 0 {
+			counts[item]--
+			continue
+		}
+		diff = append(diff, item)
+	}
+	return diff
+}
+
+// setDifference returns the sorted items of a not in b
+func setDifference(a, b map[string]struct{}) []string {
+	var diff []string
+	for item := range a {
+		if _, ok := b[item]; !ok {
+			diff = append(diff, item)
+		}
+	}
+	slices.Sort(diff)
+	return diff
+}
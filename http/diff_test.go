@@ -0,0 +1,90 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestChain(t *testing.T, status int, header http.Header, body string) *ResponseChain {
+	t.Helper()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "text/html")
+	resp := &http.Response{
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    &http.Request{Method: http.MethodGet},
+	}
+	rc := NewResponseChain(resp, 0)
+	require.NoError(t, rc.Fill())
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func TestDiff(t *testing.T) {
+	page := func(msg string) string {
+		return "<html><head><title>Search</title></head>\n<body>\n<div id=\"req-" + msg + "\">request 1234</div>\n<p>no results found</p>\n</body></html>"
+	}
+	baseline := newTestChain(t, 200, http.Header{"Date": {"Mon, 01 Jan 2024"}, "Set-Cookie": {"session=abc"}}, page("1"))
+
+	t.Run("Identical Modulo Volatile Data", func(t *testing.T) {
+		other := newTestChain(t, 200, http.Header{"Date": {"Tue, 02 Jan 2024"}, "Set-Cookie": {"session=def"}}, page("2"))
+		report := Diff(baseline, other, WithDiffTimings(100*time.Millisecond, 120*time.Millisecond, 50*time.Millisecond))
+		require.Zero(t, report.Score, report.String())
+		require.False(t, report.Different(0.05))
+	})
+
+	t.Run("Different", func(t *testing.T) {
+		other := newTestChain(t, 500, http.Header{"X-Debug": {"1"}}, "<html><body><pre>SQL syntax error near 'abc'</pre></body></html>")
+		report := Diff(baseline, other, WithDiffTimings(100*time.Millisecond, 3*time.Second, 50*time.Millisecond))
+		require.True(t, report.Different(0.5), report.String())
+
+		status, ok := report.Component(DiffStatus)
+		require.True(t, ok)
+		require.Equal(t, float64(1), status.Score)
+		require.Equal(t, []string{"status 200 => 500"}, status.Details)
+
+		headers, _ := report.Component(DiffHeaders)
+		require.Contains(t, headers.Details, "- Set-Cookie: session")
+		require.Contains(t, headers.Details, "+ X-Debug: 1")
+
+		for _, name := range []string{DiffLines, DiffWords, DiffTags, DiffSimhash, DiffTiming} {
+			c, _ := report.Component(name)
+			require.Positive(t, c.Score, name)
+		}
+		require.Contains(t, report.String(), "status 200 => 500")
+	})
+
+	t.Run("Small Change", func(t *testing.T) {
+		other := newTestChain(t, 200, nil, strings.Replace(page("1"), "no results found", "1 result found", 1))
+		report := Diff(baseline, other, WithVolatileHeaders("Set-Cookie"), WithDiffWeights(map[string]float64{DiffHeaders: 0}))
+		tags, _ := report.Component(DiffTags)
+		require.Zero(t, tags.Score)
+		lines, _ := report.Component(DiffLines)
+		require.Equal(t, []string{"1 lines removed, 1 added", "- <p>no results found</p>", "+ <p><num> result found</p>"}, lines.Details)
+		require.Less(t, report.Score, 0.3)
+	})
+
+	t.Run("Non HTML Bodies", func(t *testing.T) {
+		a := newTestChain(t, 200, nil, `{"user":"alice","role":"admin","active":true}`)
+		b := newTestChain(t, 200, nil, `{"error":"not found","code":404}`)
+		report := Diff(a, b)
+		for _, name := range []string{DiffTags, DiffTiming} {
+			c, _ := report.Component(name)
+			require.True(t, c.Skipped, name)
+		}
+		// only status, headers, lines, words and simhash apply
+		require.InDelta(t, 0.5, report.Score, 0.05, report.String())
+		require.True(t, report.Different(0.4))
+	})
+}
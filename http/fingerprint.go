@@ -0,0 +1,344 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash"
+	"github.com/projectdiscovery/utils/errkit"
+	mapsutil "github.com/projectdiscovery/utils/maps"
+)
+
+var (
+	titleRegex     = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title\s*>`)
+	linkTagRegex   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	relIconRegex   = regexp.MustCompile(`(?i)\brel\s*=\s*["']?[^"'>]*\bicon\b`)
+	hrefRegex      = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	metaGenerator  = regexp.MustCompile(`(?is)<meta[^>]+name\s*=\s*["']?generator["']?[^>]+content\s*=\s*["']([^"']+)`)
+	productToken   = regexp.MustCompile(`\([^)]*\)`)
+	volatileTokens = []struct {
+		regex       *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+		{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?\b`), "<time>"},
+		{regexp.MustCompile(`(?i)\b[0-9a-f]{16,}\b`), "<hex>"},
+		{regexp.MustCompile(`\b\d+\b`), "<num>"},
+		{regexp.MustCompile(`\s+`), " "},
+	}
+
+	// well-known headers and cookie names hinting technologies
+	// (empty technology means the header value is used)
+	techHeaders = map[string]string{
+		"X-AspNet-Version":    "ASP.NET",
+		"X-AspNetMvc-Version": "ASP.NET MVC",
+		"X-Drupal-Cache":      "Drupal",
+		"X-Generator":         "",
+		"X-Shopify-Stage":     "Shopify",
+		"X-Varnish":           "Varnish",
+		"CF-Ray":              "Cloudflare",
+		"X-Amz-Cf-Id":         "Amazon CloudFront",
+	}
+	techCookies = map[string]string{
+		"PHPSESSID":             "PHP",
+		"JSESSIONID":            "Java",
+		"ASP.NET_SessionId":     "ASP.NET",
+		"laravel_session":       "Laravel",
+		"wordpress_test_cookie": "WordPress",
+		"ci_session":            "CodeIgniter",
+		"connect.sid":           "Express",
+	}
+)
+
+// Fingerprint contains the metadata of a http response commonly used to
+// identify and compare responses between runs
+type Fingerprint struct {
+	URL        string `json:"url,omitempty"`
+	StatusCode int    `json:"status_code"`
+	Title      string `json:"title,omitempty"`
+	// FaviconURL is the url of the favicon declared in html or /favicon.ico
+	FaviconURL string `json:"favicon_url,omitempty"`
+	// FaviconMMH3 is the shodan compatible mmh3 hash of the favicon
+	FaviconMMH3 string `json:"favicon_mmh3,omitempty"`
+	// BodySimhash is the simhash of the body (see SimhashDistance)
+	BodySimhash uint64 `json:"body_simhash"`
+	// BodyHash is the xxhash of the body with volatile tokens
+	// (numbers, dates, uuids, hex ids and whitespace) normalized
+	BodyHash string `json:"body_hash"`
+	// HeaderOrder is the comma separated list of lowercase header names in
+	// wire order, it is only set when the order is known (see NewRawFingerprint)
+	HeaderOrder string `json:"header_order,omitempty"`
+	// HeaderOrderHash is the xxhash of HeaderOrder
+	HeaderOrderHash string   `json:"header_order_hash,omitempty"`
+	Server          []string `json:"server,omitempty"`
+	PoweredBy       []string `json:"powered_by,omitempty"`
+	Tech            []string `json:"tech,omitempty"`
+	ContentType     string   `json:"content_type,omitempty"`
+	ContentLength   int      `json:"content_length"`
+	Words           int      `json:"words"`
+	Lines           int      `json:"lines"`
+}
+
+// Fingerprint returns the fingerprint of the current response of the chain.
+//
+// HeaderOrder and HeaderOrderHash are not set since net/http does not preserve
+// the order of headers on the wire, use NewRawFingerprint to fingerprint it.
+func (r *ResponseChain) Fingerprint() *Fingerprint {
+	if r.resp == nil {
+		return &Fingerprint{}
+	}
+	return NewFingerprint(r.resp.Request, r.resp.StatusCode, r.resp.Header, nil, r.BodyBytes())
+}
+
+// NewRawFingerprint returns the fingerprint of a raw response preserving the
+// header order as received on the wire
+func NewRawFingerprint(req *http.Request, resp *RawResponse) (*Fingerprint, error) {
+	body, err := resp.DecodedBody()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp.Headers))
+	for _, h := range resp.Headers {
+		if !h.Malformed {
+			names = append(names, strings.TrimSpace(h.Name))
+		}
+	}
+	return NewFingerprint(req, resp.StatusCode(), resp.Header(), names, body), nil
+}
+
+// NewFingerprint returns the fingerprint of a response with given status, headers,
+// header names in wire order and (decoded) body. req is optional and used to resolve urls.
+// headerOrder should be nil unless the order of headers on the wire is known.
+func NewFingerprint(req *http.Request, statusCode int, header http.Header, headerOrder []string, body []byte) *Fingerprint {
+	f := &Fingerprint{
+		StatusCode:    statusCode,
+		ContentType:   header.Get("Content-Type"),
+		ContentLength: len(body),
+		Words:         len(bytes.Fields(body)),
+		Lines:         bytes.Count(body, []byte("\n")),
+		Server:        productTokens(header.Get("Server")),
+		PoweredBy:     productTokens(header.Get("X-Powered-By")),
+		BodySimhash:   Simhash(string(body)),
+		BodyHash:      strconv.FormatUint(xxhash.Sum64String(NormalizeBody(string(body))), 16),
+	}
+	if len(body) > 0 && !bytes.HasSuffix(body, []byte("\n")) {
+		f.Lines++
+	}
+	if len(headerOrder) > 0 {
+		names := make([]string, 0, len(headerOrder))
+		for _, name := range headerOrder {
+			names = append(names, strings.ToLower(name))
+		}
+		f.HeaderOrder = strings.Join(names, ",")
+		f.HeaderOrderHash = strconv.FormatUint(xxhash.Sum64String(f.HeaderOrder), 16)
+	}
+
+	var base *url.URL
+	if req != nil && req.URL != nil {
+		base = req.URL
+		f.URL = req.URL.String()
+	}
+	if strings.HasPrefix(f.ContentType, "image/") || (base != nil && strings.HasSuffix(base.Path, ".ico")) {
+		// response is the favicon itself
+		f.FaviconURL = f.URL
+		f.SetFavicon(body)
+	} else if isHTML(f.ContentType, body) {
+		f.Title = ExtractTitle(body)
+		f.FaviconURL = ExtractFaviconURL(base, body)
+	}
+	f.Tech = techHints(header, body)
+	return f
+}
+
+// SetFavicon sets the favicon hash from the favicon contents
+func (f *Fingerprint) SetFavicon(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	f.FaviconMMH3 = strconv.FormatInt(int64(FaviconHash(data)), 10)
+}
+
+// FetchFavicon downloads the favicon of the fingerprint and sets its hash
+func (f *Fingerprint) FetchFavicon(ctx context.Context, client *http.Client) error {
+	if f.FaviconURL == "" {
+		return errkit.New("no favicon url")
+	}
+	if f.FaviconMMH3 != "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.FaviconURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer DrainResponseBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return errkit.New("unexpected favicon status", "url", f.FaviconURL, "status_code", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxBodySize))
+	if err != nil {
+		return err
+	}
+	f.SetFavicon(data)
+	return nil
+}
+
+// Map returns the fingerprint fields as a map keyed by their json names
+// formatted with given format (ex: "%s" or "%s_1")
+func (f *Fingerprint) Map(format string) map[string]interface{} {
+	if format == "" {
+		format = "%s"
+	}
+	values := map[string]interface{}{
+		"url":               f.URL,
+		"status_code":       f.StatusCode,
+		"title":             f.Title,
+		"favicon_url":       f.FaviconURL,
+		"favicon_mmh3":      f.FaviconMMH3,
+		"body_simhash":      f.BodySimhash,
+		"body_hash":         f.BodyHash,
+		"header_order":      f.HeaderOrder,
+		"header_order_hash": f.HeaderOrderHash,
+		"server":            f.Server,
+		"powered_by":        f.PoweredBy,
+		"tech":              f.Tech,
+		"content_type":      f.ContentType,
+		"content_length":    f.ContentLength,
+		"words":             f.Words,
+		"lines":             f.Lines,
+	}
+	m := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		m[fmt.Sprintf(format, k)] = v
+	}
+	return m
+}
+
+// ToMap returns the matcher map of the current response (see maps.HTTPToMap)
+// including the fingerprint fields (prefixed with "fingerprint_")
+func (r *ResponseChain) ToMap(duration time.Duration, format string) map[string]interface{} {
+	if r.resp == nil {
+		return map[string]interface{}{}
+	}
+	if format == "" {
+		format = "%s"
+	}
+	// body was already consumed while filling the chain
+	saved := r.resp.Body
+	r.resp.Body = io.NopCloser(bytes.NewReader(r.BodyBytes()))
+	m := mapsutil.HTTPToMap(r.resp, r.BodyString(), r.HeadersString(), duration, format)
+	r.resp.Body = saved
+
+	for k, v := range r.Fingerprint().Map("fingerprint_" + format) {
+		m[k] = v
+	}
+	if protocol := r.Protocol(); protocol.ConnReused {
+		m[fmt.Sprintf(format, "conn_reused")] = protocol.ConnReused
+	}
+	return m
+}
+
+// ExtractTitle returns the html title of given body
+func ExtractTitle(body []byte) string {
+	matches := titleRegex.FindSubmatch(body)
+	if len(matches) < 2 {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(matches[1]))), " ")
+}
+
+// ExtractFaviconURL returns the absolute url of the favicon declared in html
+// or the default /favicon.ico of given base url
+func ExtractFaviconURL(base *url.URL, body []byte) string {
+	href := ""
+	for _, tag := range linkTagRegex.FindAll(body, -1) {
+		if !relIconRegex.Match(tag) {
+			continue
+		}
+		if m := hrefRegex.FindSubmatch(tag); m != nil {
+			href = string(bytes.Join(m[1:], nil))
+			break
+		}
+	}
+	if base == nil {
+		return href
+	}
+	if href == "" {
+		href = "/favicon.ico"
+	}
+	ref, err := url.Parse(strings.TrimSpace(html.UnescapeString(href)))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// NormalizeBody replaces volatile tokens (numbers, dates, uuids, hex ids)
+// with placeholders and collapses whitespace so that bodies of otherwise
+// identical responses are equal
+func NormalizeBody(body string) string {
+	for _, n := range volatileTokens {
+		body = n.regex.ReplaceAllString(body, n.replacement)
+	}
+	return strings.TrimSpace(body)
+}
+
+// productTokens splits a Server / X-Powered-By header into product tokens
+// ex: "Apache/2.4.1 (Unix) OpenSSL/1.0.2k" => [Apache/2.4.1 OpenSSL/1.0.2k]
+func productTokens(value string) []string {
+	value = productToken.ReplaceAllString(value, " ")
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' })
+}
+
+// techHints returns technologies hinted by headers, cookies and meta generator
+func techHints(header http.Header, body []byte) []string {
+	seen := map[string]struct{}{}
+	add := func(tech string) {
+		if tech = strings.TrimSpace(tech); tech != "" {
+			seen[tech] = struct{}{}
+		}
+	}
+	for name, tech := range techHeaders {
+		if value := header.Get(name); value != "" {
+			if tech == "" {
+				tech = value
+			}
+			add(tech)
+		}
+	}
+	for _, line := range header.Values("Set-Cookie") {
+		name, _, _ := strings.Cut(line, "=")
+		if tech, ok := techCookies[strings.TrimSpace(name)]; ok {
+			add(tech)
+		}
+	}
+	if m := metaGenerator.FindSubmatch(body); len(m) == 2 {
+		add(html.UnescapeString(string(m[1])))
+	}
+	techs := make([]string, 0, len(seen))
+	for tech := range seen {
+		techs = append(techs, tech)
+	}
+	sort.Strings(techs)
+	return techs
+}
+
+func isHTML(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return true
+	}
+	return contentType == "" && bytes.Contains(bytes.ToLower(body[:min(len(body), 512)]), []byte("<html"))
+}
@@ -0,0 +1,103 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMMH3(t *testing.T) {
+	require.Equal(t, int32(0), MMH3(nil))
+	require.Equal(t, int32(613153351), MMH3([]byte("hello")))
+	require.Equal(t, int32(776992547), MMH3([]byte("The quick brown fox jumps over the lazy dog")))
+}
+
+func TestSimhash(t *testing.T) {
+	a := Simhash("the quick brown fox jumps over the lazy dog near the river bank today")
+	b := Simhash("the quick brown fox jumps over the lazy dog near the river bank tonight")
+	c := Simhash("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod")
+	require.Less(t, SimhashDistance(a, b), SimhashDistance(a, c))
+	require.Equal(t, 0, SimhashDistance(a, a))
+}
+
+func TestFingerprint(t *testing.T) {
+	icon := bytes.Repeat([]byte{0x00, 0x01, 0x02}, 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/static/icon.png" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(icon)
+			return
+		}
+		w.Header().Set("Server", "Apache/2.4.1 (Unix) OpenSSL/1.0.2k")
+		w.Header().Set("X-Powered-By", "PHP/8.1")
+		w.Header().Set("Content-Type", "text/html")
+		http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "x"})
+		_, _ = w.Write([]byte("<html><head><title>\n Admin &amp; Login </title>" +
+			`<link rel="shortcut icon" href="/static/icon.png">` +
+			`<meta name="generator" content="WordPress 6.1"></head>` +
+			"<body>request 12345 at 2024-01-01T10:00:00Z</body></html>\n"))
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/login")
+	require.NoError(t, err)
+	rc := NewResponseChain(resp, -1)
+	require.NoError(t, rc.Fill())
+	defer rc.Close()
+
+	f := rc.Fingerprint()
+	require.Equal(t, 200, f.StatusCode)
+	require.Equal(t, "Admin & Login", f.Title)
+	require.Equal(t, ts.URL+"/static/icon.png", f.FaviconURL)
+	require.Equal(t, []string{"Apache/2.4.1", "OpenSSL/1.0.2k"}, f.Server)
+	require.Equal(t, []string{"PHP/8.1"}, f.PoweredBy)
+	require.Equal(t, []string{"PHP", "WordPress 6.1"}, f.Tech)
+	require.Equal(t, 2, f.Lines)
+	require.Equal(t, len(rc.BodyBytes()), f.ContentLength)
+	require.Empty(t, f.HeaderOrder, "net/http does not preserve the header order")
+	require.Empty(t, f.HeaderOrderHash)
+
+	// volatile tokens do not change the normalized body hash
+	other := NewFingerprint(nil, 200, http.Header{"Content-Type": {"text/html"}}, nil,
+		bytes.Replace(rc.BodyBytes(), []byte("12345"), []byte("67890"), 1))
+	require.Equal(t, f.BodyHash, other.BodyHash)
+
+	require.NoError(t, f.FetchFavicon(context.Background(), http.DefaultClient))
+	require.NotEmpty(t, f.FaviconMMH3)
+
+	// fetching the favicon directly yields the same hash
+	resp, err = http.Get(f.FaviconURL)
+	require.NoError(t, err)
+	iconChain := NewResponseChain(resp, -1)
+	require.NoError(t, iconChain.Fill())
+	defer iconChain.Close()
+	require.Equal(t, f.FaviconMMH3, iconChain.Fingerprint().FaviconMMH3)
+
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+	restored := &Fingerprint{}
+	require.NoError(t, json.Unmarshal(data, restored))
+	require.Equal(t, f, restored)
+
+	t.Run("Map", func(t *testing.T) {
+		m := rc.ToMap(time.Second, "")
+		require.Equal(t, "Admin & Login", m["fingerprint_title"])
+		require.Equal(t, 200, m["status_code"])
+		require.Contains(t, m["raw"], "Admin &amp; Login")
+	})
+}
+
+func TestFingerprintRawHeaderOrder(t *testing.T) {
+	raw, err := ParseRawResponse([]byte("HTTP/1.1 200 OK\r\nServer: nginx\r\nDate: x\r\nContent-Type: text/plain\r\n\r\nok"))
+	require.NoError(t, err)
+	f, err := NewRawFingerprint(nil, raw)
+	require.NoError(t, err)
+	require.Equal(t, "server,date,content-type", f.HeaderOrder)
+	require.Equal(t, []string{"nginx"}, f.Server)
+}
@@ -0,0 +1,601 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// HARVersion is the version of HAR format generated by this package
+const HARVersion = "1.2"
+
+// DefaultHARCreator is the creator used for generated HAR logs
+var DefaultHARCreator = HARCreator{Name: "projectdiscovery/utils", Version: "1.0"}
+
+// HAR is the root object of a HTTP Archive (HAR 1.2)
+// http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the log object of a HAR
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator is the creator of a HAR log
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request/response exchange
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+}
+
+// HARNameValue is a name/value pair used for headers, query strings and params
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARCookie is a cookie of a request or response
+type HARCookie struct {
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Path     string     `json:"path,omitempty"`
+	Domain   string     `json:"domain,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	HTTPOnly bool       `json:"httpOnly,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+}
+
+// HARRequest is the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []HARNameValue `json:"params,omitempty"`
+	Text     string         `json:"text"`
+	// Encoding is an extension of HAR 1.2 used for binary request bodies
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARResponse is the response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARContent is the body of a response
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	// Encoding is "base64" for binary content
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings contains the timings of an entry in milliseconds
+// -1 is used for timings that do not apply
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// total returns the sum of all applicable timings
+func (t HARTimings) total() float64 {
+	total := 0.0
+	// connect already includes ssl as per spec
+	for _, v := range []float64{t.Blocked, t.DNS, t.Connect, t.Send, t.Wait, t.Receive} {
+		if v > 0 {
+			total += v
+		}
+	}
+	return total
+}
+
+// NewHAR creates a new HAR with given entries
+func NewHAR(entries ...HAREntry) *HAR {
+	if entries == nil {
+		entries = []HAREntry{}
+	}
+	return &HAR{Log: HARLog{Version: HARVersion, Creator: DefaultHARCreator, Entries: entries}}
+}
+
+// ReadHAR reads a HAR from given reader
+func ReadHAR(r io.Reader) (*HAR, error) {
+	har := &HAR{}
+	if err := json.NewDecoder(r).Decode(har); err != nil {
+		return nil, errkit.Wrap(err, "could not decode har")
+	}
+	return har, nil
+}
+
+// HARExchange is a request/response pair restored from a HAR entry
+type HARExchange struct {
+	Request  *http.Request
+	Response *http.Response
+}
+
+// Exchanges converts all entries of the HAR to request/response pairs
+func (h *HAR) Exchanges() ([]HARExchange, error) {
+	exchanges := make([]HARExchange, 0, len(h.Log.Entries))
+	for i := range h.Log.Entries {
+		req, err := h.Log.Entries[i].ToRequest()
+		if err != nil {
+			return nil, errkit.Wrapf(err, "could not convert entry %d", i)
+		}
+		resp, err := h.Log.Entries[i].ToResponse(req)
+		if err != nil {
+			return nil, errkit.Wrapf(err, "could not convert entry %d", i)
+		}
+		exchanges = append(exchanges, HARExchange{Request: req, Response: resp})
+	}
+	return exchanges, nil
+}
+
+// ToRequest converts the entry request to a *http.Request which can be replayed
+func (e *HAREntry) ToRequest() (*http.Request, error) {
+	var body []byte
+	if pd := e.Request.PostData; pd != nil {
+		var err error
+		switch {
+		case pd.Text == "" && len(pd.Params) > 0:
+			values := url.Values{}
+			for _, p := range pd.Params {
+				values.Add(p.Name, p.Value)
+			}
+			body = []byte(values.Encode())
+		case strings.EqualFold(pd.Encoding, "base64"):
+			if body, err = base64.StdEncoding.DecodeString(pd.Text); err != nil {
+				return nil, errkit.Wrap(err, "could not decode request body")
+			}
+		default:
+			body = []byte(pd.Text)
+		}
+	}
+	req, err := http.NewRequest(e.Request.Method, e.Request.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	setHARProto(e.Request.HTTPVersion, &req.Proto, &req.ProtoMajor, &req.ProtoMinor)
+	for _, h := range e.Request.Headers {
+		switch {
+		case strings.HasPrefix(h.Name, ":"):
+			// http/2 pseudo headers
+			if h.Name == ":authority" {
+				req.Host = h.Value
+			}
+		case strings.EqualFold(h.Name, "Host"):
+			req.Host = h.Value
+		case strings.EqualFold(h.Name, "Content-Length"):
+			// computed from body
+		default:
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	return req, nil
+}
+
+// ToResponse converts the entry response to a *http.Response for given request
+// the body is stored decoded in HAR so Content-Encoding header is removed and
+// Content-Length is updated to match the body
+func (e *HAREntry) ToResponse(req *http.Request) (*http.Response, error) {
+	body := []byte(e.Response.Content.Text)
+	if strings.EqualFold(e.Response.Content.Encoding, "base64") {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+			return nil, errkit.Wrap(err, "could not decode response body")
+		}
+	}
+	resp := &http.Response{
+		StatusCode:    e.Response.Status,
+		Status:        strings.TrimSpace(strconv.Itoa(e.Response.Status) + " " + e.Response.StatusText),
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	setHARProto(e.Response.HTTPVersion, &resp.Proto, &resp.ProtoMajor, &resp.ProtoMinor)
+	for _, h := range e.Response.Headers {
+		if strings.HasPrefix(h.Name, ":") {
+			continue
+		}
+		resp.Header.Add(h.Name, h.Value)
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Transfer-Encoding")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+// HAREntries converts all hops of the chain, from the first request up to
+// the current response, to HAR entries. Body of the current response is only
+// available after Fill() was called (redirect hops do not have body).
+//
+// Timings are included if the request was created with WithHARTrace.
+func (r *ResponseChain) HAREntries() ([]HAREntry, error) {
+	if r.resp == nil {
+		return nil, errkit.New("response is nil")
+	}
+	hops := []*http.Response{}
+	for resp := r.resp; resp != nil; {
+		hops = append(hops, resp)
+		if resp.Request == nil {
+			break
+		}
+		resp = resp.Request.Response
+	}
+
+	trace := r.harTrace()
+	entries := make([]HAREntry, 0, len(hops))
+	for i := len(hops) - 1; i >= 0; i-- {
+		var body []byte
+		if i == 0 && !r.reloaded && r.body != nil {
+			body = r.body.Bytes()
+		}
+		entry, err := newHAREntry(hops[i], body)
+		if err != nil {
+			return nil, err
+		}
+		if trace != nil {
+			trace.apply(len(entries), &entry)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// currentHAREntry converts only the current response of the chain to a HAR
+// entry, unlike HAREntries previous hops of the redirect chain are skipped
+func (r *ResponseChain) currentHAREntry() (HAREntry, error) {
+	if r.resp == nil {
+		return HAREntry{}, errkit.New("response is nil")
+	}
+	var body []byte
+	if !r.reloaded && r.body != nil {
+		body = r.body.Bytes()
+	}
+	entry, err := newHAREntry(r.resp, body)
+	if err != nil {
+		return entry, err
+	}
+	if trace := r.harTrace(); trace != nil {
+		// index of the current hop is the number of previous hops
+		index := 0
+		for req := r.resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+			index++
+		}
+		trace.apply(index, &entry)
+	}
+	return entry, nil
+}
+
+// harTrace returns the trace attached to the request by WithHARTrace (if any)
+func (r *ResponseChain) harTrace() *harTrace {
+	if req := r.resp.Request; req != nil {
+		trace, _ := req.Context().Value(harTraceKey{}).(*harTrace)
+		return trace
+	}
+	return nil
+}
+
+// HAR returns the chain as a HAR log
+func (r *ResponseChain) HAR() (*HAR, error) {
+	entries, err := r.HAREntries()
+	if err != nil {
+		return nil, err
+	}
+	return NewHAR(entries...), nil
+}
+
+// newHAREntry creates a HAR entry for given response
+func newHAREntry(resp *http.Response, body []byte) (HAREntry, error) {
+	entry := HAREntry{
+		StartedDateTime: time.Now(),
+		Timings:         HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1},
+	}
+	if req := resp.Request; req != nil {
+		harReq, err := newHARRequest(req)
+		if err != nil {
+			return entry, err
+		}
+		entry.Request = harReq
+	}
+
+	entry.Response = HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: protoString(resp.Proto, resp.ProtoMajor, resp.ProtoMinor),
+		Cookies:     harCookies(resp.Cookies()),
+		Headers:     harHeaders(resp.Header, ""),
+		Content: HARContent{
+			Size:     len(body),
+			MimeType: resp.Header.Get("Content-Type"),
+		},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if status := strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode))); status != "" {
+		entry.Response.StatusText = status
+	}
+	if resp.ContentLength >= 0 {
+		entry.Response.BodySize = int(resp.ContentLength)
+	}
+	if len(body) > 0 {
+		entry.Response.Content.Text, entry.Response.Content.Encoding = harText(body)
+	}
+	entry.Response.RedirectURL = resp.Header.Get("Location")
+	if resp.TLS != nil {
+		entry.Connection = tls.VersionName(resp.TLS.Version)
+	}
+	return entry, nil
+}
+
+// newHARRequest creates a HAR request for given http request
+func newHARRequest(req *http.Request) (HARRequest, error) {
+	harReq := HARRequest{
+		Method:      req.Method,
+		HTTPVersion: protoString(req.Proto, req.ProtoMajor, req.ProtoMinor),
+		Cookies:     harCookies(req.Cookies()),
+		Headers:     harHeaders(req.Header, req.Host),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+	}
+	if req.Method == "" {
+		harReq.Method = http.MethodGet
+	}
+	if req.URL != nil {
+		harReq.URL = req.URL.String()
+		query := req.URL.Query()
+		keys := make([]string, 0, len(query))
+		for k := range query {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range query[k] {
+				harReq.QueryString = append(harReq.QueryString, HARNameValue{Name: k, Value: v})
+			}
+		}
+	}
+	// only replayable bodies are read to avoid consuming the request body
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return harReq, errkit.Wrap(err, "could not read request body")
+		}
+		body, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return harReq, errkit.Wrap(err, "could not read request body")
+		}
+		harReq.BodySize = len(body)
+		if len(body) > 0 {
+			pd := &HARPostData{MimeType: req.Header.Get("Content-Type")}
+			pd.Text, pd.Encoding = harText(body)
+			harReq.PostData = pd
+		}
+	}
+	return harReq, nil
+}
+
+// harHeaders converts headers to sorted name/value pairs
+func harHeaders(header http.Header, host string) []HARNameValue {
+	values := []HARNameValue{}
+	if host != "" && header.Get("Host") == "" {
+		values = append(values, HARNameValue{Name: "Host", Value: host})
+	}
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range header[k] {
+			values = append(values, HARNameValue{Name: k, Value: v})
+		}
+	}
+	return values
+}
+
+func harCookies(cookies []*http.Cookie) []HARCookie {
+	values := make([]HARCookie, 0, len(cookies))
+	for _, c := range cookies {
+		hc := HARCookie{Name: c.Name, Value: c.Value, Path: c.Path, Domain: c.Domain, HTTPOnly: c.HttpOnly, Secure: c.Secure}
+		if !c.Expires.IsZero() {
+			expires := c.Expires
+			hc.Expires = &expires
+		}
+		values = append(values, hc)
+	}
+	return values
+}
+
+// harText returns body as text and its encoding (base64 for binary data)
+func harText(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func protoString(proto string, major, minor int) string {
+	if proto != "" {
+		return proto
+	}
+	if major == 0 {
+		return "HTTP/1.1"
+	}
+	return "HTTP/" + strconv.Itoa(major) + "." + strconv.Itoa(minor)
+}
+
+func setHARProto(version string, proto *string, major, minor *int) {
+	version = strings.ToUpper(version)
+	if version == "" || version == "UNKNOWN" {
+		version = "HTTP/1.1"
+	}
+	if version == "H2" || version == "HTTP/2" {
+		version = "HTTP/2.0"
+	}
+	if maj, min, ok := http.ParseHTTPVersion(version); ok {
+		*proto, *major, *minor = version, maj, min
+		return
+	}
+	*proto, *major, *minor = "HTTP/1.1", 1, 1
+}
+
+type harTraceKey struct{}
+
+// harHop contains the trace timestamps of a single hop
+type harHop struct {
+	start, dnsStart, dnsDone, connectStart, connectDone time.Time
+	tlsStart, tlsDone, gotConn, wroteRequest, firstByte time.Time
+	remoteAddr                                          string
+}
+
+// harTrace records timings of all hops of a request
+type harTrace struct {
+	mu   sync.Mutex
+	hops []*harHop
+}
+
+// WithHARTrace returns a shallow copy of the request with a httptrace
+// attached so that timings of all hops are included in HAR entries
+func WithHARTrace(req *http.Request) *http.Request {
+	trace := &harTrace{}
+	current := func() *harHop {
+		if len(trace.hops) == 0 {
+			trace.hops = append(trace.hops, &harHop{start: time.Now()})
+		}
+		return trace.hops[len(trace.hops)-1]
+	}
+	set := func(fn func(h *harHop)) {
+		trace.mu.Lock()
+		defer trace.mu.Unlock()
+		fn(current())
+	}
+	clientTrace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.hops = append(trace.hops, &harHop{start: time.Now()})
+		},
+		DNSStart:          func(httptrace.DNSStartInfo) { set(func(h *harHop) { h.dnsStart = time.Now() }) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(func(h *harHop) { h.dnsDone = time.Now() }) },
+		ConnectStart:      func(string, string) { set(func(h *harHop) { h.connectStart = time.Now() }) },
+		ConnectDone:       func(string, string, error) { set(func(h *harHop) { h.connectDone = time.Now() }) },
+		TLSHandshakeStart: func() { set(func(h *harHop) { h.tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(func(h *harHop) { h.tlsDone = time.Now() }) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			set(func(h *harHop) {
+				h.gotConn = time.Now()
+				if info.Conn != nil {
+					h.remoteAddr = info.Conn.RemoteAddr().String()
+				}
+			})
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(func(h *harHop) { h.wroteRequest = time.Now() }) },
+		GotFirstResponseByte: func() { set(func(h *harHop) { h.firstByte = time.Now() }) },
+	}
+	ctx := context.WithValue(req.Context(), harTraceKey{}, trace)
+	return req.WithContext(httptrace.WithClientTrace(ctx, clientTrace))
+}
+
+// apply sets timings of given hop to the entry
+func (t *harTrace) apply(index int, entry *HAREntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if index >= len(t.hops) {
+		return
+	}
+	h := t.hops[index]
+	entry.StartedDateTime = h.start
+	if host, _, err := net.SplitHostPort(h.remoteAddr); err == nil {
+		entry.ServerIPAddress = host
+	}
+
+	timings := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	firstNetworkEvent := h.gotConn
+	for _, ts := range []time.Time{h.connectStart, h.dnsStart} {
+		if !ts.IsZero() && ts.Before(firstNetworkEvent) {
+			firstNetworkEvent = ts
+		}
+	}
+	timings.Blocked = millis(h.start, firstNetworkEvent)
+	if !h.dnsStart.IsZero() {
+		timings.DNS = millis(h.dnsStart, h.dnsDone)
+	}
+	if !h.connectStart.IsZero() {
+		connectDone := h.connectDone
+		if h.tlsDone.After(connectDone) {
+			connectDone = h.tlsDone
+		}
+		timings.Connect = millis(h.connectStart, connectDone)
+	}
+	if !h.tlsStart.IsZero() {
+		timings.SSL = millis(h.tlsStart, h.tlsDone)
+	}
+	timings.Send = max(millis(h.gotConn, h.wroteRequest), 0)
+	timings.Wait = max(millis(h.wroteRequest, h.firstByte), 0)
+	entry.Timings = timings
+	entry.Time = timings.total()
+}
+
+// millis returns the duration between two timestamps in milliseconds
+// or -1 if any of them is missing
+func millis(start, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() {
+		return -1
+	}
+	return float64(end.Sub(start)) / float64(time.Millisecond)
+}
@@ -0,0 +1,130 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newHARTestServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc"})
+			http.Redirect(w, r, "/final?x=1", http.StatusFound)
+		case "/binary":
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00, 0x01})
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Encoding", "gzip")
+			gw := gzip.NewWriter(w)
+			_, _ = gw.Write([]byte("hello " + r.URL.Query().Get("x")))
+			_ = gw.Close()
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func fetchChain(t *testing.T, req *http.Request) *ResponseChain {
+	// disable transparent decompression to exercise normalization
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(WithHARTrace(req))
+	require.NoError(t, err)
+	rc := NewResponseChain(resp, -1)
+	require.NoError(t, rc.Fill())
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func TestResponseChainHAR(t *testing.T) {
+	ts := newHARTestServer(t)
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/redirect", strings.NewReader("a=b"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rc := fetchChain(t, req)
+
+	entries, err := rc.HAREntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	first, last := entries[0], entries[1]
+	require.Equal(t, http.MethodPost, first.Request.Method)
+	require.Equal(t, "a=b", first.Request.PostData.Text)
+	require.Equal(t, http.StatusFound, first.Response.Status)
+	require.Equal(t, "/final?x=1", first.Response.RedirectURL)
+	require.Equal(t, "sid", first.Response.Cookies[0].Name)
+	require.Empty(t, first.Response.Content.Text)
+
+	require.Equal(t, http.MethodGet, last.Request.Method)
+	require.Equal(t, []HARNameValue{{Name: "x", Value: "1"}}, last.Request.QueryString)
+	require.Equal(t, "hello 1", last.Response.Content.Text)
+	require.Equal(t, "text/plain", last.Response.Content.MimeType)
+	require.Equal(t, "127.0.0.1", last.ServerIPAddress)
+	require.GreaterOrEqual(t, last.Timings.Wait, 0.0)
+	require.False(t, last.StartedDateTime.Before(first.StartedDateTime))
+
+	t.Run("Binary Body", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/binary", nil)
+		entries, err := fetchChain(t, req).HAREntries()
+		require.NoError(t, err)
+		require.Equal(t, "base64", entries[0].Response.Content.Encoding)
+		require.Equal(t, "//4AAQ==", entries[0].Response.Content.Text)
+	})
+}
+
+func TestHARWriterAndReplay(t *testing.T) {
+	ts := newHARTestServer(t)
+	filename := filepath.Join(t.TempDir(), "scan.har")
+
+	w, err := NewHARWriter(filename)
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/redirect", nil)
+	require.NoError(t, w.WriteChain(fetchChain(t, req)))
+	require.NoError(t, w.Close())
+
+	// reopen and append, file must stay valid after every write
+	w, err = NewHARWriter(filename)
+	require.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodGet, ts.URL+"/binary", nil)
+	require.NoError(t, w.WriteChain(fetchChain(t, req)))
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	har, err := ReadHAR(f)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, HARVersion, har.Log.Version)
+	require.Len(t, har.Log.Entries, 3)
+
+	exchanges, err := har.Exchanges()
+	require.NoError(t, err)
+	require.Len(t, exchanges, 3)
+	require.Equal(t, ts.URL+"/final?x=1", exchanges[1].Request.URL.String())
+
+	// restored response is decoded and can be loaded into a response chain
+	require.Empty(t, exchanges[1].Response.Header.Get("Content-Encoding"))
+	rc := NewResponseChain(exchanges[1].Response, -1)
+	require.NoError(t, rc.Fill())
+	require.Equal(t, "hello 1", rc.BodyString())
+	rc.Close()
+
+	body, err := io.ReadAll(exchanges[2].Response.Body)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xff, 0xfe, 0x00, 0x01}, body)
+
+	// replay against server
+	resp, err := http.DefaultClient.Do(exchanges[2].Request)
+	require.NoError(t, err)
+	DrainResponseBody(resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
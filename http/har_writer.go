@@ -0,0 +1,147 @@
+package httputil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// harTrailer closes the entries array and the log/root objects
+var harTrailer = []byte("\n]}}\n")
+
+// HARWriter appends HAR entries to a file while keeping it a valid HAR
+// after every write, so that partial results survive a crashed scan
+//
+//	w, _ := httputil.NewHARWriter("scan.har")
+//	defer w.Close()
+//	_ = w.WriteChain(rc)
+type HARWriter struct {
+	mu      sync.Mutex
+	file    *os.File
+	offset  int64
+	entries int
+}
+
+// NewHARWriter opens given HAR file for appending entries, creating it if necessary.
+// Existing files must end with the entries array (as written by HARWriter or most tools).
+// New files are created with 0600 permissions since entries contain cookies and credentials.
+func NewHARWriter(filename string) (*HARWriter, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w := &HARWriter{file: file}
+	if err := w.init(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// init writes the HAR header to empty files or locates the end
+// of the entries array of existing files
+func (w *HARWriter) init() error {
+	info, err := w.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		header, err := json.Marshal(NewHAR())
+		if err != nil {
+			return err
+		}
+		// strip `]}}` of the empty entries array
+		header = bytes.TrimSuffix(header, []byte("]}}"))
+		if _, err := w.file.Write(header); err != nil {
+			return err
+		}
+		w.offset = int64(len(header))
+		return w.writeTrailer()
+	}
+
+	// scan backwards from end skipping closing braces and whitespace
+	// until the closing bracket of entries array
+	size := info.Size()
+	tail := make([]byte, min(size, 4096))
+	if _, err := w.file.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return err
+	}
+	i := len(tail) - 1
+	for i >= 0 && bytes.IndexByte([]byte("} \t\r\n"), tail[i]) >= 0 {
+		i--
+	}
+	if i < 0 || tail[i] != ']' {
+		return errkit.New("invalid har file: could not find end of entries", "file", w.file.Name())
+	}
+	w.offset = size - int64(len(tail)) + int64(i)
+	// check whether entries array is empty
+	j := i - 1
+	for j >= 0 && bytes.IndexByte([]byte(" \t\r\n"), tail[j]) >= 0 {
+		j--
+	}
+	if j < 0 || tail[j] != '[' {
+		w.entries = 1
+	}
+	return nil
+}
+
+// Write appends given entries to the HAR file
+func (w *HARWriter) Write(entries ...HAREntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return errkit.New("har writer is closed")
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return errkit.Wrap(err, "could not marshal har entry")
+		}
+		if w.entries > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+		buf.Write(data)
+		w.entries++
+	}
+	if _, err := w.file.WriteAt(buf.Bytes(), w.offset); err != nil {
+		return err
+	}
+	w.offset += int64(buf.Len())
+	return w.writeTrailer()
+}
+
+// WriteChain appends all hops of given response chain to the HAR file
+func (w *HARWriter) WriteChain(rc *ResponseChain) error {
+	entries, err := rc.HAREntries()
+	if err != nil {
+		return err
+	}
+	return w.Write(entries...)
+}
+
+// Close closes the HAR file
+func (w *HARWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// writeTrailer writes the closing trailer at current offset
+// and truncates anything after it
+func (w *HARWriter) writeTrailer() error {
+	if _, err := w.file.WriteAt(harTrailer, w.offset); err != nil {
+		return err
+	}
+	return w.file.Truncate(w.offset + int64(len(harTrailer)))
+}
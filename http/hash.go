@@ -0,0 +1,111 @@
+package httputil
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"strings"
+	"unicode"
+
+	"github.com/cespare/xxhash"
+)
+
+// MMH3 returns the 32-bit murmur3 hash (x86 variant, seed 0) of data
+func MMH3(data []byte) int32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	var h uint32
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[nblocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return int32(h)
+}
+
+// FaviconHash returns the favicon hash as computed by shodan
+// i.e mmh3 of the base64 encoded favicon with a newline every 76 chars
+func FaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var sb strings.Builder
+	sb.Grow(len(encoded) + len(encoded)/76 + 1)
+	for len(encoded) > 76 {
+		sb.WriteString(encoded[:76])
+		sb.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	sb.WriteString(encoded)
+	sb.WriteByte('\n')
+	return MMH3([]byte(sb.String()))
+}
+
+// Simhash returns the 64-bit simhash of given text using word trigrams as features
+// similar texts have hashes with a small hamming distance (see SimhashDistance)
+func Simhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	addFeature := func(feature string) {
+		h := xxhash.Sum64String(feature)
+		for i := 0; i < 64; i++ {
+			if h&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(words) < 3 {
+		addFeature(strings.Join(words, " "))
+	}
+	for i := 0; i+3 <= len(words); i++ {
+		addFeature(words[i] + " " + words[i+1] + " " + words[i+2])
+	}
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash
+}
+
+// SimhashDistance returns the hamming distance between two simhashes (0-64)
+func SimhashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
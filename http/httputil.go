@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httputil"
+
+	mapsutil "github.com/projectdiscovery/utils/maps"
 )
 
 // AllHTTPMethods contains all available HTTP methods
@@ -40,6 +42,9 @@ func DumpResponseHeadersAndRaw(resp *http.Response) (headers, fullresp []byte, e
 		}
 		return []byte(raw), []byte(raw), nil
 	}
+	if resp.ProtoMajor >= 2 {
+		return dumpHTTP1StyleHeadersAndRaw(resp)
+	}
 	headers, err = httputil.DumpResponse(resp, false)
 	if err != nil {
 		return
@@ -66,3 +71,25 @@ func DumpResponseHeadersAndRaw(resp *http.Response) (headers, fullresp []byte, e
 	resp.Body = io.NopCloser(bytes.NewReader(buf1.Bytes()))
 	return
 }
+
+// dumpHTTP1StyleHeadersAndRaw is DumpResponseHeadersAndRaw for http/2 and
+// http/3 responses which are rendered using http/1 text framing
+func dumpHTTP1StyleHeadersAndRaw(resp *http.Response) (headers, fullresp []byte, err error) {
+	var headersBuf, fullBuf bytes.Buffer
+	if err = mapsutil.WriteHTTP1Style(resp, nil, &headersBuf); err != nil {
+		return
+	}
+	var body bytes.Buffer
+	if resp.Body != nil {
+		if _, err = body.ReadFrom(resp.Body); err != nil && body.Len() <= 0 {
+			return
+		}
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		// rewind to allow further reuses
+		resp.Body = io.NopCloser(bytes.NewReader(body.Bytes()))
+	}
+	err = mapsutil.WriteHTTP1Style(resp, body.Bytes(), &fullBuf)
+	return headersBuf.Bytes(), fullBuf.Bytes(), err
+}
@@ -0,0 +1,336 @@
+package httputil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// Middleware wraps a http.RoundTripper with additional behaviour
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to use ordinary functions as http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain returns a http.RoundTripper applying given middlewares around base
+// the first middleware is the outermost one (i.e. it sees the request first).
+// If base is nil http.DefaultTransport is used.
+//
+//	client := &http.Client{
+//		Transport: httputil.Chain(nil,
+//			httputil.RetryMiddleware(errkit.NewRetrier()),
+//			httputil.RateLimitMiddleware(10, 1),
+//			httputil.BodySizeMiddleware(0),
+//		),
+//	}
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rt := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// RateLimitMiddleware limits requests per host (host:port of the url) to rps
+// requests per second allowing bursts of given size (min 1).
+// Requests wait for their turn until their context is done.
+func RateLimitMiddleware(rps float64, burst int) Middleware {
+	limiter := newHostLimiter(rps, burst)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := limiter.wait(req.Context(), req.URL.Host); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RetryMiddleware retries requests failing with errors the retrier considers
+// retryable (see errkit.Retrier). Responses with given status codes (ex: 429, 503)
+// are retried as temporary network errors and the last one is returned once
+// retries are exhausted. Requests with bodies are only retried if their body
+// can be recreated (i.e. req.GetBody is set).
+func RetryMiddleware(retrier *errkit.Retrier, retryStatusCodes ...int) Middleware {
+	if retrier == nil {
+		retrier = errkit.NewRetrier()
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+				return next.RoundTrip(req)
+			}
+			var (
+				resp        *http.Response
+				attempts    int
+				statusRetry bool
+			)
+			err := retrier.Do(req.Context(), func(ctx context.Context) error {
+				attempts++
+				if resp != nil {
+					// discard the response of the previous attempt
+					DrainResponseBody(resp)
+					resp = nil
+				}
+				attemptReq := req
+				if attempts > 1 && req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return errkit.Wrap(err, "could not recreate request body")
+					}
+					attemptReq = req.Clone(ctx)
+					attemptReq.Body = body
+				}
+				var err error
+				resp, err = next.RoundTrip(attemptReq)
+				statusRetry = err == nil && slices.Contains(retryStatusCodes, resp.StatusCode)
+				if statusRetry {
+					return errkit.New("retryable status code", "status_code", resp.StatusCode).SetKind(errkit.ErrKindNetworkTemporary)
+				}
+				return err
+			})
+			if err != nil && statusRetry {
+				// retries exhausted on status code, return last response as is
+				return resp, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		})
+	}
+}
+
+// HeaderMiddleware adds given headers to every request. Existing headers of
+// the request are kept unless override is true. The request is cloned and
+// never modified.
+func HeaderMiddleware(headers http.Header, override bool) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			for name, values := range headers {
+				if !override && len(req.Header.Values(name)) > 0 {
+					continue
+				}
+				req.Header.Del(name)
+				for _, v := range values {
+					req.Header.Add(name, v)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RecorderMiddleware calls fn with a filled ResponseChain of every response.
+// Up to maxBody bytes of the body (DefaultMaxBodySize if <= 0) are recorded
+// while the caller still receives the whole body. The chain is closed once fn returns.
+func RecorderMiddleware(maxBody int64, fn func(rc *ResponseChain)) Middleware {
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBodySize
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp == nil {
+				return resp, err
+			}
+			var recorded []byte
+			if resp.Body != nil && resp.Body != http.NoBody {
+				buf := getBuffer()
+				defer putBuffer(buf)
+				_, readErr := io.Copy(buf, io.LimitReader(resp.Body, maxBody))
+				recorded = append([]byte{}, buf.Bytes()...)
+				// caller receives the recorded prefix followed by the rest of the body
+				body := resp.Body
+				resp.Body = &readCloser{
+					Reader: io.MultiReader(bytes.NewReader(recorded), &errReader{r: body, err: readErr}),
+					close:  body.Close,
+				}
+			}
+			recordedResp := *resp
+			recordedResp.Header = resp.Header.Clone()
+			recordedResp.Body = io.NopCloser(bytes.NewReader(recorded))
+			rc := NewResponseChain(&recordedResp, maxBody)
+			defer rc.Close()
+			if fillErr := rc.Fill(); fillErr == nil {
+				fn(rc)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// HARRecorderMiddleware records every response to given HAR writer. The
+// middleware runs once per hop so redirects result in one entry per hop.
+func HARRecorderMiddleware(w *HARWriter) Middleware {
+	return RecorderMiddleware(0, func(rc *ResponseChain) {
+		// previous hops were already recorded by earlier round trips
+		if entry, err := rc.currentHAREntry(); err == nil {
+			_ = w.Write(entry)
+		}
+	})
+}
+
+// BodySizeMiddleware fails responses with bodies larger than max bytes with an
+// error of kind errkit.ErrKindHTTPBodyTooLarge. If max is less than or equal to
+// zero, it defaults to [DefaultMaxBodySize] which is the largest body the
+// response buffer pool keeps.
+func BodySizeMiddleware(max int64) Middleware {
+	if max <= 0 {
+		max = DefaultMaxBodySize
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp == nil {
+				return resp, err
+			}
+			if resp.ContentLength > max {
+				_ = resp.Body.Close()
+				return nil, bodyTooLargeError(req, max, resp.ContentLength)
+			}
+			if resp.Body != nil && resp.Body != http.NoBody {
+				resp.Body = &maxBodyReader{ReadCloser: resp.Body, remaining: max, max: max, req: req}
+			}
+			return resp, nil
+		})
+	}
+}
+
+func bodyTooLargeError(req *http.Request, max, size int64) error {
+	args := []any{"url", req.URL.String(), "max_body_size", max}
+	if size > 0 {
+		args = append(args, "content_length", size)
+	}
+	return errkit.New("response body too large", args...).SetKind(errkit.ErrKindHTTPBodyTooLarge)
+}
+
+// maxBodyReader fails once more than max bytes are read
+type maxBodyReader struct {
+	io.ReadCloser
+	remaining int64
+	max       int64
+	req       *http.Request
+}
+
+func (m *maxBodyReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, bodyTooLargeError(m.req, m.max, -1)
+	}
+	// read one extra byte to detect bodies exceeding the limit
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.ReadCloser.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n + int(m.remaining), bodyTooLargeError(m.req, m.max, -1)
+	}
+	return n, err
+}
+
+// readCloser combines a reader with a close function
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error { return r.close() }
+
+// errReader returns err (if not nil) instead of reading from r
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return e.r.Read(p)
+}
+
+// hostLimiterSweepInterval is the minimum interval between evictions of idle buckets
+const hostLimiterSweepInterval = time.Minute
+
+// hostLimiter is a token bucket rate limiter per host
+type hostLimiter struct {
+	mu        sync.Mutex
+	rps       float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newHostLimiter(rps float64, burst int) *hostLimiter {
+	return &hostLimiter{rps: rps, burst: float64(max(burst, 1)), buckets: map[string]*tokenBucket{}, now: time.Now}
+}
+
+// wait blocks until a token for given host is available
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.rps <= 0 {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := l.now()
+		l.sweep(now)
+		b, ok := l.buckets[host]
+		if !ok {
+			b = &tokenBucket{tokens: l.burst, last: now}
+			l.buckets[host] = b
+		}
+		b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rps)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - b.tokens) / l.rps * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// sweep evicts buckets which were refilled completely since they are
+// equivalent to new ones, it must be called with the lock held
+func (l *hostLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < hostLimiterSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for host, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rps >= l.burst {
+			delete(l.buckets, host)
+		}
+	}
+}
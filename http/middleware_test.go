@@ -0,0 +1,242 @@
+package httputil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/projectdiscovery/utils/errkit"
+	"github.com/stretchr/testify/require"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func fastRetrier(attempts int) *errkit.Retrier {
+	return errkit.NewRetrier(errkit.WithRetryPolicy(errkit.ErrKindNetworkTemporary, errkit.RetryPolicy{
+		MaxAttempts:  attempts,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Millisecond,
+		Multiplier:   1,
+	}))
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: Chain(nil, mark("a"), mark("b"))}
+	resp, err := client.Get(ts.URL)
+	require.NoError(t, err)
+	DrainResponseBody(resp)
+	require.Equal(t, []string{"a", "b"}, order)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	ts1, ts2 := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer ts1.Close()
+	defer ts2.Close()
+
+	client := &http.Client{Transport: Chain(nil, RateLimitMiddleware(20, 1))}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(ts1.URL)
+		require.NoError(t, err)
+		DrainResponseBody(resp)
+	}
+	require.GreaterOrEqual(t, time.Since(start), 140*time.Millisecond)
+
+	// other hosts have their own bucket
+	start = time.Now()
+	resp, err := client.Get(ts2.URL)
+	require.NoError(t, err)
+	DrainResponseBody(resp)
+	require.Less(t, time.Since(start), 40*time.Millisecond)
+
+	t.Run("Evicts Idle Hosts", func(t *testing.T) {
+		now := time.Now()
+		limiter := newHostLimiter(10, 1)
+		limiter.now = func() time.Time { return now }
+		for _, host := range []string{"a", "b", "c"} {
+			require.NoError(t, limiter.wait(context.Background(), host))
+		}
+		require.Len(t, limiter.buckets, 3)
+		now = now.Add(2 * hostLimiterSweepInterval)
+		require.NoError(t, limiter.wait(context.Background(), "d"))
+		require.Len(t, limiter.buckets, 1)
+	})
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, "payload", string(body))
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	t.Run("Status Codes", func(t *testing.T) {
+		client := &http.Client{Transport: Chain(nil, RetryMiddleware(fastRetrier(3), http.StatusServiceUnavailable))}
+		resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		require.Equal(t, "ok", string(body))
+		require.Equal(t, int32(3), calls.Load())
+	})
+
+	t.Run("Exhausted Returns Last Response", func(t *testing.T) {
+		calls.Store(0)
+		client := &http.Client{Transport: Chain(nil, RetryMiddleware(fastRetrier(2), http.StatusServiceUnavailable))}
+		resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		DrainResponseBody(resp)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+
+	t.Run("Transport Errors", func(t *testing.T) {
+		calls.Store(10)
+		failures := 0
+		flaky := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if failures < 2 {
+				failures++
+				return nil, timeoutError{}
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		client := &http.Client{Transport: Chain(flaky, RetryMiddleware(fastRetrier(3)))}
+		resp, err := client.Post(ts.URL, "text/plain", strings.NewReader("payload"))
+		require.NoError(t, err)
+		DrainResponseBody(resp)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// permanent errors are not retried
+		failures = 0
+		refused := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			failures++
+			return nil, errkit.New("connection refused")
+		})
+		client = &http.Client{Transport: Chain(refused, RetryMiddleware(fastRetrier(3)))}
+		_, err = client.Get(ts.URL)
+		require.Error(t, err)
+		require.Equal(t, 1, failures)
+	})
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Scan") + "|" + r.Header.Get("User-Agent")))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: Chain(nil, HeaderMiddleware(http.Header{"X-Scan": {"1"}, "User-Agent": {"scanner"}}, false))}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	req.Header.Set("User-Agent", "custom")
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	require.Equal(t, "1|custom", string(body))
+	require.Empty(t, req.Header.Get("X-Scan"), "request must not be modified")
+}
+
+func TestRecorderMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	}))
+	defer ts.Close()
+
+	var recorded []string
+	client := &http.Client{Transport: Chain(nil, RecorderMiddleware(10, func(rc *ResponseChain) {
+		recorded = append(recorded, rc.Request().URL.Path+" "+rc.BodyString())
+	}))}
+	resp, err := client.Get(ts.URL + "/path")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Len(t, body, 100, "caller must receive the whole body")
+	require.Equal(t, []string{"/path " + strings.Repeat("a", 10)}, recorded)
+}
+
+func TestHARRecorderMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/a" {
+			http.Redirect(w, r, "/b", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	filename := filepath.Join(t.TempDir(), "recorded.har")
+	w, err := NewHARWriter(filename)
+	require.NoError(t, err)
+	client := &http.Client{Transport: Chain(nil, HARRecorderMiddleware(w))}
+	resp, err := client.Get(ts.URL + "/a")
+	require.NoError(t, err)
+	DrainResponseBody(resp)
+	require.NoError(t, w.Close())
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	har, err := ReadHAR(f)
+	require.NoError(t, err)
+	require.Len(t, har.Log.Entries, 2, "each hop must be recorded once")
+	require.Equal(t, http.StatusFound, har.Log.Entries[0].Response.Status)
+	require.Equal(t, ts.URL+"/b", har.Log.Entries[1].Request.URL)
+	require.Equal(t, http.StatusOK, har.Log.Entries[1].Response.Status)
+}
+
+func TestBodySizeMiddleware(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			for i := 0; i < 4; i++ {
+				_, _ = w.Write([]byte(strings.Repeat("a", 512)))
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+		_, _ = w.Write([]byte(strings.Repeat("a", 2048)))
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: Chain(nil, BodySizeMiddleware(1024))}
+	_, err := client.Get(ts.URL)
+	require.Error(t, err)
+	require.True(t, errkit.IsKind(err, errkit.ErrKindHTTPBodyTooLarge))
+
+	resp, err := client.Get(ts.URL + "/chunked")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.Error(t, err)
+	require.True(t, errkit.IsKind(err, errkit.ErrKindHTTPBodyTooLarge))
+	require.Len(t, body, 1024)
+	_ = resp.Body.Close()
+}
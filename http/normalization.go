@@ -1,22 +1,23 @@
 package httputil
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dsnet/compress/brotli"
+	"github.com/klauspost/compress/flate"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zlib"
 	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
-	"golang.org/x/text/encoding/charmap"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 
+	"github.com/projectdiscovery/utils/errkit"
 	stringsutil "github.com/projectdiscovery/utils/strings"
 )
 
@@ -28,6 +29,58 @@ const (
 	DefaultChunkSize = 32 * 1024 // 32KB
 )
 
+const (
+	// DefaultMaxDecompressionRatio is the default maximum ratio between the
+	// decompressed and compressed size of a response body.
+	//
+	// Use [SetMaxDecompressionRatio] to adjust the ratio.
+	DefaultMaxDecompressionRatio = 200
+
+	// DefaultMaxDecompressedSize is the default maximum number of bytes
+	// decompressed from a response body.
+	//
+	// Use [SetMaxDecompressedSize] to adjust the size.
+	DefaultMaxDecompressedSize = 128 * 1024 * 1024 // 128 MB
+
+	// decompressionRatioMinSize is the number of decompressed bytes after
+	// which the decompression ratio is checked, small bodies of repeated
+	// content legitimately have high ratios.
+	decompressionRatioMinSize = 1024 * 1024 // 1 MB
+)
+
+// decompression limits are read by concurrently filled response chains
+var (
+	maxDecompressionRatio atomic.Int64
+	maxDecompressedSize   atomic.Int64
+)
+
+func init() {
+	maxDecompressionRatio.Store(DefaultMaxDecompressionRatio)
+	maxDecompressedSize.Store(DefaultMaxDecompressedSize)
+}
+
+// SetMaxDecompressionRatio sets the maximum ratio between the decompressed
+// and compressed size of response bodies. Reading bodies exceeding it fails
+// with an error of kind errkit.ErrKindHTTPBodyTooLarge.
+//
+// If ratio is less than or equal to zero, the ratio is not checked.
+func SetMaxDecompressionRatio(ratio int) {
+	maxDecompressionRatio.Store(int64(ratio))
+}
+
+// SetMaxDecompressedSize sets the maximum number of bytes decompressed from
+// response bodies. Reading bodies exceeding it fails with an error of kind
+// errkit.ErrKindHTTPBodyTooLarge.
+//
+// The size is raised to the maximum body size of response chains allowing
+// larger bodies, and is not checked for streamed response chains whose bodies
+// are spilled to disk (see WithStreaming).
+//
+// If size is less than or equal to zero, the size is not checked.
+func SetMaxDecompressedSize(size int64) {
+	maxDecompressedSize.Store(size)
+}
+
 var (
 	chunkSize = DefaultChunkSize
 	chunkPool = sync.Pool{
@@ -93,6 +146,20 @@ func (lb *limitedBuffer) ReadFrom(r io.Reader) (n int64, err error) {
 	}
 }
 
+// limitedWriter writes to buf until max bytes were written
+// and silently discards the rest
+type limitedWriter struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if remaining := lw.max - lw.buf.Len(); remaining > 0 {
+		lw.buf.Write(p[:min(len(p), remaining)])
+	}
+	return len(p), nil
+}
+
 // readNNormalizeRespBody performs normalization on the http response object.
 // and fills body buffer with actual response body.
 func readNNormalizeRespBody(rc *ResponseChain, body *bytes.Buffer) (err error) {
@@ -109,22 +176,59 @@ func readNNormalizeRespBody(rc *ResponseChain, body *bytes.Buffer) (err error) {
 		// skip normalization if body is nil
 		return nil
 	}
+	// the decompressed size is capped by the larger of the global limit and
+	// the body size, streamed bodies are spilled to disk and bounded by the
+	// body size only
+	maxSize := maxDecompressedSize.Load()
+	if rc.stream != nil {
+		maxSize = 0
+	} else if maxSize > 0 && rc.maxBodySize > maxSize {
+		maxSize = rc.maxBodySize
+	}
 	// wrap with decode if applicable
-	wrapped, err := wrapDecodeReader(response)
+	wrapped, err := wrapDecodeReader(response, maxSize)
 	if err != nil {
 		wrapped = origBody
 	}
-	limitReader := io.LimitReader(wrapped, rc.maxBodySize)
+	if wrapped != origBody {
+		defer func() {
+			_ = wrapped.Close()
+		}()
+	}
+	var decoded io.Reader = wrapped
+	if rc.rawBody != nil {
+		// keep decompressed bytes before transcoding
+		rawMax := int(rc.maxBodySize)
+		if rc.stream != nil {
+			rawMax = rc.stream.memLimit
+		}
+		decoded = io.TeeReader(wrapped, &limitedWriter{buf: rc.rawBody, max: rawMax})
+	}
+	transcoded, charset := wrapCharsetReader(decoded, response.Header.Get("Content-Type"))
+	rc.charset = charset
+	var limitReader io.Reader = io.LimitReader(transcoded, rc.maxBodySize)
+	if rc.hash != nil {
+		limitReader = io.TeeReader(limitReader, rc.hash)
+	}
 
 	// Read body using ReadFrom for efficiency, but cap growth at maxBodySize.
 	// We use a custom limitedBuffer wrapper to prevent bytes.Buffer from
 	// over-allocating (it normally grows to 2x when size is unknown).
-	limitedBuf := &limitedBuffer{buf: body, maxCap: int(rc.maxBodySize)}
-	_, err = limitedBuf.ReadFrom(limitReader)
+	// In streaming mode only a prefix is kept in memory and the rest is
+	// spilled to disk.
+	var dst io.ReaderFrom = &limitedBuffer{buf: body, maxCap: int(rc.maxBodySize)}
+	if rc.stream != nil {
+		dst = rc.stream
+	}
+	_, err = dst.ReadFrom(limitReader)
 	if err != nil {
 		if strings.Contains(err.Error(), "gzip: invalid header") {
 			// its invalid gzip but we will still use it from original body
-			_, gErr := body.ReadFrom(origBody)
+			var fallback io.Reader = origBody
+			if rc.hash != nil {
+				fallback = io.TeeReader(origBody, rc.hash)
+			}
+			_, gErr := dst.ReadFrom(fallback)
 			if gErr != nil {
 				return errors.Wrap(gErr, "could not read response body after gzip error")
 			}
@@ -142,38 +246,155 @@ func readNNormalizeRespBody(rc *ResponseChain, body *bytes.Buffer) (err error) {
 	return nil
 }
 
-// wrapDecodeReader wraps a decompression reader around the response body if it's compressed
-// using gzip or deflate.
-func wrapDecodeReader(resp *http.Response) (rc io.ReadCloser, err error) {
-	switch resp.Header.Get("Content-Encoding") {
-	case "gzip":
-		rc, err = gzip.NewReader(resp.Body)
+// wrapDecodeReader wraps decompression readers around the response body if it's compressed
+// using gzip, deflate, br or zstd. Closing it releases the decoders but does not
+// close the response body. Stacked encodings (ex: "gzip, br") are decoded in
+// reverse order of application and the decompressed body is guarded against
+// decompression bombs (see SetMaxDecompressionRatio) and capped at maxSize bytes
+// unless it is less than or equal to zero.
+// Charset transcoding is handled by wrapCharsetReader.
+func wrapDecodeReader(resp *http.Response, maxSize int64) (rc io.ReadCloser, err error) {
+	encodings := contentEncodings(resp.Header)
+	if len(encodings) == 0 {
+		return resp.Body, nil
+	}
+	compressed := &countingReader{r: resp.Body}
+	var (
+		r       io.Reader = compressed
+		closers []io.Closer
+	)
+	closeAll := func() error {
+		var errs []error
+		for i := len(closers) - 1; i >= 0; i-- {
+			if err := closers[i].Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errkit.Join(errs...)
+	}
+	for i := len(encodings) - 1; i >= 0; i-- {
+		next, ok, err := newDecodeReader(encodings[i], r)
+		if err != nil {
+			_ = closeAll()
+			return nil, err
+		}
+		if !ok {
+			// unsupported encoding, inner layers can't be decoded either
+			break
+		}
+		r = next
+		closers = append(closers, next)
+	}
+	if len(closers) == 0 {
+		return resp.Body, nil
+	}
+	return &readCloser{
+		Reader: &decompressionGuard{r: r, compressed: compressed, maxRatio: maxDecompressionRatio.Load(), maxSize: maxSize},
+		close:  closeAll,
+	}, nil
+}
+
+// contentEncodings returns the lowercase content encodings of the header in
+// order of application ignoring identity
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+	return encodings
+}
+
+// newDecodeReader returns a reader decoding given content encoding or false if
+// the encoding is not supported
+func newDecodeReader(encoding string, r io.Reader) (io.ReadCloser, bool, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, true, err
+		}
+		return gr, true, nil
 	case "deflate":
-		rc, err = zlib.NewReader(resp.Body)
+		// deflate should be zlib wrapped (RFC 9110) but some servers send raw deflate
+		br := bufio.NewReader(r)
+		if header, err := br.Peek(2); err == nil && !isZlibHeader(header) {
+			return flate.NewReader(br), true, nil
+		}
+		zr, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, true, err
+		}
+		return zr, true, nil
 	case "br":
-		rc, err = brotli.NewReader(resp.Body, nil)
+		br, err := brotli.NewReader(r, nil)
+		if err != nil {
+			return nil, true, err
+		}
+		return br, true, nil
 	case "zstd":
-		var zstdReader *zstd.Decoder
-		zstdReader, err = zstd.NewReader(resp.Body)
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
 		if err != nil {
-			return nil, err
+			return nil, true, err
 		}
-		rc = io.NopCloser(zstdReader)
+		return zr.IOReadCloser(), true, nil
 	default:
-		rc = resp.Body
+		return nil, false, nil
 	}
-	if err != nil {
-		return nil, err
+}
+
+// isZlibHeader checks if given bytes are a valid zlib header (RFC 1950)
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && b[0]>>4 <= 7 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decompressionGuard fails with an error of kind errkit.ErrKindHTTPBodyTooLarge
+// once more than maxSize bytes are decompressed or the decompression ratio
+// exceeds maxRatio (checked after decompressionRatioMinSize bytes)
+type decompressionGuard struct {
+	r          io.Reader
+	compressed *countingReader
+	maxRatio   int64
+	maxSize    int64
+	n          int64
+	err        error
+}
+
+func (g *decompressionGuard) Read(p []byte) (int, error) {
+	if g.err != nil {
+		return 0, g.err
 	}
-	// handle GBK encoding
-	if isContentTypeGbk(resp.Header.Get("Content-Type")) {
-		rc = io.NopCloser(transform.NewReader(rc, simplifiedchinese.GBK.NewDecoder()))
+	n, err := g.r.Read(p)
+	g.n += int64(n)
+	switch {
+	case g.maxSize > 0 && g.n > g.maxSize:
+		g.err = errkit.New("decompressed body too large", "max_decompressed_size", g.maxSize).SetKind(errkit.ErrKindHTTPBodyTooLarge)
+	case g.maxRatio > 0 && g.n > decompressionRatioMinSize && g.n > g.maxRatio*max(g.compressed.n, 1):
+		g.err = errkit.New("decompressed body too large", "max_decompression_ratio", g.maxRatio, "compressed_size", g.compressed.n, "decompressed_size", g.n).SetKind(errkit.ErrKindHTTPBodyTooLarge)
 	}
-	// handle Windows-1251 encoding
-	if isContentTypeWindows1251(resp.Header.Get("Content-Type")) {
-		rc = io.NopCloser(transform.NewReader(rc, charmap.Windows1251.NewDecoder()))
+	if g.err != nil {
+		return n, g.err
 	}
-	return rc, nil
+	return n, err
 }
 
 // isContentTypeGbk checks if the content-type header is gbk
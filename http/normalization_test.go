@@ -0,0 +1,149 @@
+package httputil
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/projectdiscovery/utils/errkit"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, err = flate.NewWriter(&buf, flate.BestCompression)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf)
+	}
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func newEncodedResponse(contentEncoding string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Encoding": {contentEncoding}, "Content-Type": {"text/plain"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+	}
+}
+
+func TestWrapDecodeReader(t *testing.T) {
+	data := []byte(strings.Repeat("hello world ", 100))
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+	}{
+		{"gzip", "gzip", compress(t, "gzip", data)},
+		{"zlib deflate", "deflate", compress(t, "deflate", data)},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", data)},
+		{"stacked", "gzip, zstd", compress(t, "zstd", compress(t, "gzip", data))},
+		{"stacked with identity", "identity, Deflate ,gzip", compress(t, "gzip", compress(t, "raw-deflate", data))},
+		{"unknown", "compress", data},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newEncodedResponse(tt.contentEncoding, tt.body)
+			rc, err := wrapDecodeReader(resp, DefaultMaxDecompressedSize)
+			require.NoError(t, err)
+			decoded, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.Equal(t, data, decoded)
+			require.NoError(t, rc.Close())
+		})
+	}
+
+	t.Run("Stacked Multiple Headers", func(t *testing.T) {
+		resp := newEncodedResponse("gzip", compress(t, "zstd", compress(t, "gzip", data)))
+		resp.Header.Add("Content-Encoding", "zstd")
+		rc, err := wrapDecodeReader(resp, DefaultMaxDecompressedSize)
+		require.NoError(t, err)
+		decoded, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.Equal(t, data, decoded)
+	})
+}
+
+func TestDecompressionGuard(t *testing.T) {
+	bomb := compress(t, "gzip", make([]byte, 8*1024*1024))
+
+	t.Run("Ratio", func(t *testing.T) {
+		rc := NewResponseChain(newEncodedResponse("gzip", bomb), 16*1024*1024)
+		defer rc.Close()
+		err := rc.Fill()
+		require.Error(t, err)
+		require.True(t, errkit.IsKind(err, errkit.ErrKindHTTPBodyTooLarge), err.Error())
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		SetMaxDecompressionRatio(0)
+		defer SetMaxDecompressionRatio(DefaultMaxDecompressionRatio)
+		resp := newEncodedResponse("gzip", bomb)
+		rc, err := wrapDecodeReader(resp, 1024*1024)
+		require.NoError(t, err)
+		n, err := io.Copy(io.Discard, rc)
+		require.True(t, errkit.IsKind(err, errkit.ErrKindHTTPBodyTooLarge))
+		require.LessOrEqual(t, n, int64(1024*1024+DefaultChunkSize))
+	})
+
+	t.Run("Size Below Body Size", func(t *testing.T) {
+		SetMaxDecompressionRatio(0)
+		SetMaxDecompressedSize(1024 * 1024)
+		defer func() {
+			SetMaxDecompressionRatio(DefaultMaxDecompressionRatio)
+			SetMaxDecompressedSize(DefaultMaxDecompressedSize)
+		}()
+		rc := NewResponseChain(newEncodedResponse("gzip", bomb), 16*1024*1024)
+		defer rc.Close()
+		require.NoError(t, rc.Fill())
+		require.Equal(t, 8*1024*1024, rc.Body().Len())
+
+		// bodies larger than the body size are truncated
+		rc = NewResponseChain(newEncodedResponse("gzip", bomb), 4*1024*1024)
+		defer rc.Close()
+		require.NoError(t, rc.Fill())
+		require.Equal(t, 4*1024*1024, rc.Body().Len())
+	})
+
+	t.Run("Size Streaming", func(t *testing.T) {
+		data := make([]byte, 4*1024*1024)
+		_, _ = rand.New(rand.NewSource(1)).Read(data)
+		SetMaxDecompressedSize(1024 * 1024)
+		defer SetMaxDecompressedSize(DefaultMaxDecompressedSize)
+		rc := NewResponseChain(newEncodedResponse("gzip", compress(t, "gzip", data)), 32*1024*1024, WithStreaming(1024))
+		defer rc.Close()
+		require.NoError(t, rc.Fill())
+		require.True(t, rc.IsSpilled())
+		require.Equal(t, int64(len(data)), rc.BodySize())
+	})
+
+	t.Run("Within Limits", func(t *testing.T) {
+		data := []byte(strings.Repeat("a", 2*1024*1024))
+		rc := NewResponseChain(newEncodedResponse("gzip", compress(t, "gzip", data)), 0)
+		defer rc.Close()
+		SetMaxDecompressionRatio(0)
+		defer SetMaxDecompressionRatio(DefaultMaxDecompressionRatio)
+		require.NoError(t, rc.Fill())
+		require.Equal(t, len(data), rc.Body().Len())
+	})
+}
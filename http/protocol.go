@@ -0,0 +1,201 @@
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProtocolInfo contains protocol level metadata of a single hop.
+//
+// Http/2 stream ids are not available: net/http does not expose the stream
+// a request was sent on and the http2 transport has no per-request hook
+// reporting it.
+type ProtocolInfo struct {
+	// Proto is the protocol version of the response (ex: HTTP/1.1, HTTP/2.0, HTTP/3.0)
+	Proto      string `json:"proto"`
+	ProtoMajor int    `json:"proto_major"`
+	ProtoMinor int    `json:"proto_minor"`
+	// ALPN is the protocol negotiated during the tls handshake (ex: h2, http/1.1, h3)
+	ALPN string   `json:"alpn,omitempty"`
+	TLS  *TLSInfo `json:"tls,omitempty"`
+	// ConnReused is true if the request was sent on a reused connection (traced requests only)
+	ConnReused bool `json:"conn_reused,omitempty"`
+	// PseudoHeaders contains the http/2 and http/3 pseudo headers of the
+	// request (:method, :scheme, :authority, :path) and response (:status)
+	PseudoHeaders map[string]string `json:"pseudo_headers,omitempty"`
+}
+
+// TLSInfo contains the tls connection state of a hop
+type TLSInfo struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipher_suite"`
+	ServerName  string    `json:"server_name,omitempty"`
+	Resumed     bool      `json:"resumed,omitempty"`
+	SubjectCN   string    `json:"subject_cn,omitempty"`
+	IssuerCN    string    `json:"issuer_cn,omitempty"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotAfter    time.Time `json:"not_after,omitzero"`
+}
+
+// NewTLSInfo returns the tls info of given connection state (nil if state is nil)
+func NewTLSInfo(state *tls.ConnectionState) *TLSInfo {
+	if state == nil {
+		return nil
+	}
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		Resumed:     state.DidResume,
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.SubjectCN = cert.Subject.CommonName
+		info.IssuerCN = cert.Issuer.CommonName
+		info.DNSNames = cert.DNSNames
+		info.NotAfter = cert.NotAfter
+	}
+	return info
+}
+
+// NewProtocolInfo returns the protocol metadata of given response.
+// Connection reuse is only available if the request was traced with
+// WithProtocolTrace.
+func NewProtocolInfo(resp *http.Response) ProtocolInfo {
+	info := ProtocolInfo{
+		Proto:      protoString(resp.Proto, resp.ProtoMajor, resp.ProtoMinor),
+		ProtoMajor: resp.ProtoMajor,
+		ProtoMinor: resp.ProtoMinor,
+		TLS:        NewTLSInfo(resp.TLS),
+	}
+	if resp.TLS != nil {
+		info.ALPN = resp.TLS.NegotiatedProtocol
+	}
+	if resp.ProtoMajor >= 2 {
+		info.PseudoHeaders = pseudoHeaders(resp)
+	}
+	if resp.Request != nil {
+		if trace, ok := resp.Request.Context().Value(protoTraceKey{}).(*protoTrace); ok {
+			trace.apply(hopIndex(resp), &info)
+		}
+	}
+	return info
+}
+
+// Protocol returns the protocol metadata of the current response in the chain
+func (r *ResponseChain) Protocol() ProtocolInfo {
+	if r.resp == nil {
+		return ProtocolInfo{}
+	}
+	return NewProtocolInfo(r.resp)
+}
+
+// Protocols returns the protocol metadata of all hops of the chain from the
+// first request up to the current response
+func (r *ResponseChain) Protocols() []ProtocolInfo {
+	var infos []ProtocolInfo
+	for resp := r.resp; resp != nil; {
+		infos = append(infos, NewProtocolInfo(resp))
+		if resp.Request == nil {
+			break
+		}
+		resp = resp.Request.Response
+	}
+	for i, j := 0, len(infos)-1; i < j; i, j = i+1, j-1 {
+		infos[i], infos[j] = infos[j], infos[i]
+	}
+	return infos
+}
+
+// hopIndex returns the position of the response in its redirect chain
+func hopIndex(resp *http.Response) int {
+	index := 0
+	for resp.Request != nil && resp.Request.Response != nil {
+		resp = resp.Request.Response
+		index++
+	}
+	return index
+}
+
+// pseudoHeaders returns the http/2 pseudo headers of the response and its request
+func pseudoHeaders(resp *http.Response) map[string]string {
+	headers := map[string]string{":status": strconv.Itoa(resp.StatusCode)}
+	req := resp.Request
+	if req == nil || req.URL == nil {
+		return headers
+	}
+	headers[":method"] = req.Method
+	authority := req.Host
+	if authority == "" {
+		authority = req.URL.Host
+	}
+	headers[":authority"] = authority
+	if req.Method != http.MethodConnect {
+		headers[":scheme"] = req.URL.Scheme
+		headers[":path"] = req.URL.RequestURI()
+	}
+	return headers
+}
+
+type protoTraceKey struct{}
+
+// protoHop contains the connection metadata of a single hop
+type protoHop struct {
+	alpn   string
+	reused bool
+}
+
+// protoTrace records connection metadata of all hops of a request
+type protoTrace struct {
+	mu   sync.Mutex
+	hops []*protoHop
+}
+
+// WithProtocolTrace returns a shallow copy of the request with a httptrace
+// attached recording the negotiated protocol and connection reuse of all
+// hops (see ResponseChain.Protocols). Http/2 stream ids are not recorded
+// (see ProtocolInfo).
+func WithProtocolTrace(req *http.Request) *http.Request {
+	trace := &protoTrace{}
+	clientTrace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			trace.hops = append(trace.hops, &protoHop{})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			hop := &protoHop{reused: info.Reused}
+			if conn, ok := info.Conn.(*tls.Conn); ok {
+				hop.alpn = conn.ConnectionState().NegotiatedProtocol
+			}
+			trace.mu.Lock()
+			defer trace.mu.Unlock()
+			if len(trace.hops) == 0 {
+				trace.hops = append(trace.hops, hop)
+				return
+			}
+			trace.hops[len(trace.hops)-1] = hop
+		},
+	}
+	ctx := context.WithValue(req.Context(), protoTraceKey{}, trace)
+	return req.WithContext(httptrace.WithClientTrace(ctx, clientTrace))
+}
+
+// apply sets the connection metadata of given hop to info
+func (t *protoTrace) apply(index int, info *ProtocolInfo) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if index >= len(t.hops) {
+		return
+	}
+	h := t.hops[index]
+	if info.ALPN == "" {
+		info.ALPN = h.alpn
+	}
+	info.ConnReused = h.reused
+}
@@ -0,0 +1,84 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newHTTP2TestServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/stream", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		// flush to send the body without content-length
+		_, _ = w.Write([]byte("hello "))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("world"))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestProtocolInfo(t *testing.T) {
+	ts := newHTTP2TestServer(t)
+	client := ts.Client()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/redirect?x=1", nil)
+		resp, err := client.Do(WithProtocolTrace(req))
+		require.NoError(t, err)
+
+		rc := NewResponseChain(resp, 0)
+		require.NoError(t, rc.Fill())
+		info := rc.Protocol()
+		require.Equal(t, "HTTP/2.0", info.Proto)
+		require.Equal(t, "h2", info.ALPN)
+		require.NotNil(t, info.TLS)
+		require.Equal(t, "TLS 1.3", info.TLS.Version)
+		require.Equal(t, "200", info.PseudoHeaders[":status"])
+		require.Equal(t, "/stream", info.PseudoHeaders[":path"])
+		require.Equal(t, "https", info.PseudoHeaders[":scheme"])
+
+		hops := rc.Protocols()
+		require.Len(t, hops, 2)
+		require.Equal(t, "302", hops[0].PseudoHeaders[":status"])
+		require.Equal(t, "/redirect?x=1", hops[0].PseudoHeaders[":path"])
+		require.True(t, hops[1].ConnReused)
+
+		require.Equal(t, "HTTP/2.0 200 OK\r\nContent-Type: text/plain\r\n", rc.HeadersString()[:len("HTTP/2.0 200 OK\r\nContent-Type: text/plain\r\n")])
+		require.NotContains(t, rc.HeadersString(), "Connection")
+
+		m := rc.ToMap(0, "")
+		require.Equal(t, "HTTP/2.0", m["proto"])
+		require.Equal(t, "h2", m["alpn"])
+		require.Equal(t, "TLS 1.3", m["tls_version"])
+		require.Equal(t, "200", m["pseudo_status"])
+		require.Equal(t, "/stream", m["pseudo_path"])
+		require.Equal(t, true, m["conn_reused"])
+		require.NotContains(t, m["raw"], "Connection")
+		require.True(t, strings.HasPrefix(m["raw"].(string), "HTTP/2.0 200 OK\r\nContent-Length: 11\r\nContent-Type: text/plain\r\n"), m["raw"])
+		require.True(t, strings.HasSuffix(m["raw"].(string), "\r\n\r\nhello world"))
+		rc.Close()
+	}
+}
+
+func TestDumpResponseHeadersAndRawHTTP2(t *testing.T) {
+	ts := newHTTP2TestServer(t)
+	resp, err := ts.Client().Get(ts.URL)
+	require.NoError(t, err)
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	headers, raw, err := DumpResponseHeadersAndRaw(resp)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(headers), "HTTP/2.0 200 OK\r\nContent-Type: text/plain\r\nDate: "))
+	require.True(t, strings.HasPrefix(string(raw), "HTTP/2.0 200 OK\r\nContent-Length: 11\r\nContent-Type: text/plain\r\n"))
+	require.True(t, strings.HasSuffix(string(raw), "\r\n\r\nhello world"))
+}
@@ -0,0 +1,413 @@
+package httputil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+// RawHeader is a header line of a raw http message as seen on the wire
+type RawHeader struct {
+	// Name is the header name with original casing
+	Name string
+	// Value is the header value without surrounding whitespace
+	// (folded continuation lines are joined with a single space)
+	Value string
+	// Line is the exact header line (including folded continuation lines)
+	// without the trailing line ending. If empty the line is generated
+	// from Name and Value while writing.
+	Line string
+	// EOL is the line ending of the header line ("\r\n" or "\n")
+	EOL string
+	// Malformed is true if the line is not a valid `name: value` header
+	Malformed bool
+}
+
+// line returns the header line as written on the wire
+func (h RawHeader) line() string {
+	if h.Line != "" || h.Malformed {
+		return h.Line
+	}
+	return h.Name + ": " + h.Value
+}
+
+// RawMessage is a raw http/1.x message preserving its exact wire form
+// (header order, duplicates, casing, whitespace, line endings and malformed lines)
+type RawMessage struct {
+	// StartLine is the request or status line without line ending
+	StartLine string
+	// StartLineEOL is the line ending of the start line
+	StartLineEOL string
+	// Headers are all header lines in order
+	Headers []RawHeader
+	// HeadersEnd is the empty line terminating headers ("\r\n", "\n" or "" if missing)
+	HeadersEnd string
+	// Body is the body as is (chunked encoding is not decoded)
+	Body []byte
+}
+
+// RawRequest is a raw http/1.x request
+type RawRequest struct {
+	RawMessage
+}
+
+// RawResponse is a raw http/1.x response
+type RawResponse struct {
+	RawMessage
+}
+
+// ParseRawRequest parses a raw http/1.x request (ex: from a proxy export)
+func ParseRawRequest(data []byte) (*RawRequest, error) {
+	msg, err := parseRawMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	req := &RawRequest{RawMessage: *msg}
+	if req.Method() == "" {
+		return nil, errkit.New("invalid request line", "line", msg.StartLine)
+	}
+	return req, nil
+}
+
+// ParseRawResponse parses a raw http/1.x response
+func ParseRawResponse(data []byte) (*RawResponse, error) {
+	msg, err := parseRawMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	resp := &RawResponse{RawMessage: *msg}
+	if !strings.HasPrefix(resp.Proto(), "HTTP/") {
+		return nil, errkit.New("invalid status line", "line", msg.StartLine)
+	}
+	return resp, nil
+}
+
+// parseRawMessage splits raw data into start line, header lines and body
+func parseRawMessage(data []byte) (*RawMessage, error) {
+	msg := &RawMessage{}
+	nextLine := func() (string, string, bool) {
+		if len(data) == 0 {
+			return "", "", false
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			line := string(data)
+			data = nil
+			return line, "", true
+		}
+		line, eol := string(data[:i]), "\n"
+		if strings.HasSuffix(line, "\r") {
+			line, eol = line[:len(line)-1], "\r\n"
+		}
+		data = data[i+1:]
+		return line, eol, true
+	}
+
+	var ok bool
+	msg.StartLine, msg.StartLineEOL, ok = nextLine()
+	if !ok || strings.TrimSpace(msg.StartLine) == "" {
+		return nil, errkit.New("missing start line")
+	}
+	for {
+		line, eol, ok := nextLine()
+		if !ok {
+			break
+		}
+		if line == "" {
+			msg.HeadersEnd = eol
+			break
+		}
+		// obsolete line folding continues the previous header
+		if (line[0] == ' ' || line[0] == '\t') && len(msg.Headers) > 0 && !msg.Headers[len(msg.Headers)-1].Malformed {
+			prev := &msg.Headers[len(msg.Headers)-1]
+			prev.Line += prev.EOL + line
+			prev.Value = strings.TrimSpace(prev.Value + " " + strings.TrimSpace(line))
+			prev.EOL = eol
+			continue
+		}
+		header := RawHeader{Line: line, EOL: eol}
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) == "" {
+			header.Malformed = true
+		} else {
+			header.Name = name
+			header.Value = strings.TrimSpace(value)
+		}
+		msg.Headers = append(msg.Headers, header)
+	}
+	if len(data) > 0 {
+		msg.Body = append([]byte{}, data...)
+	}
+	return msg, nil
+}
+
+// Bytes returns the message in its wire form
+func (m *RawMessage) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(m.StartLine)
+	buf.WriteString(m.StartLineEOL)
+	for _, h := range m.Headers {
+		buf.WriteString(h.line())
+		buf.WriteString(h.EOL)
+	}
+	buf.WriteString(m.HeadersEnd)
+	buf.Write(m.Body)
+	return buf.Bytes()
+}
+
+// String returns the message in its wire form
+func (m *RawMessage) String() string {
+	return string(m.Bytes())
+}
+
+// WriteTo writes the message in its wire form to given writer
+func (m *RawMessage) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(m.Bytes())
+	return int64(n), err
+}
+
+// Get returns the value of the first header with given name (case-insensitive)
+func (m *RawMessage) Get(name string) string {
+	for _, h := range m.Headers {
+		if !h.Malformed && strings.EqualFold(strings.TrimSpace(h.Name), name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// Values returns values of all headers with given name (case-insensitive)
+func (m *RawMessage) Values(name string) []string {
+	values := []string{}
+	for _, h := range m.Headers {
+		if !h.Malformed && strings.EqualFold(strings.TrimSpace(h.Name), name) {
+			values = append(values, h.Value)
+		}
+	}
+	return values
+}
+
+// Set replaces the value of the first header with given name keeping its
+// position and casing and removes other occurrences. If there is no such
+// header it is appended.
+func (m *RawMessage) Set(name, value string) {
+	found := false
+	headers := m.Headers[:0]
+	for _, h := range m.Headers {
+		if !h.Malformed && strings.EqualFold(strings.TrimSpace(h.Name), name) {
+			if found {
+				continue
+			}
+			found = true
+			h.Value, h.Line = value, ""
+		}
+		headers = append(headers, h)
+	}
+	m.Headers = headers
+	if !found {
+		m.Add(name, value)
+	}
+}
+
+// Add appends a header with given name and value as is
+func (m *RawMessage) Add(name, value string) {
+	m.Headers = append(m.Headers, RawHeader{Name: name, Value: value, EOL: m.eol()})
+}
+
+// Del removes all headers with given name (case-insensitive)
+func (m *RawMessage) Del(name string) {
+	headers := m.Headers[:0]
+	for _, h := range m.Headers {
+		if !h.Malformed && strings.EqualFold(strings.TrimSpace(h.Name), name) {
+			continue
+		}
+		headers = append(headers, h)
+	}
+	m.Headers = headers
+}
+
+// SetBody replaces the body and updates Content-Length header if present
+func (m *RawMessage) SetBody(body []byte) {
+	m.Body = body
+	if m.Get("Content-Length") != "" {
+		m.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	if m.HeadersEnd == "" {
+		m.HeadersEnd = m.eol()
+	}
+}
+
+// Header returns headers as http.Header (malformed lines are skipped)
+func (m *RawMessage) Header() http.Header {
+	header := http.Header{}
+	for _, h := range m.Headers {
+		if h.Malformed {
+			continue
+		}
+		header.Add(strings.TrimSpace(h.Name), h.Value)
+	}
+	return header
+}
+
+// DecodedBody returns the body with chunked transfer encoding removed
+func (m *RawMessage) DecodedBody() ([]byte, error) {
+	if !strings.Contains(strings.ToLower(m.Get("Transfer-Encoding")), "chunked") {
+		return m.Body, nil
+	}
+	body, err := io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(m.Body)))
+	if err != nil {
+		return body, errkit.Wrap(err, "could not decode chunked body")
+	}
+	return body, nil
+}
+
+// eol returns the line ending used by the message
+func (m *RawMessage) eol() string {
+	if m.StartLineEOL != "" {
+		return m.StartLineEOL
+	}
+	return "\r\n"
+}
+
+// startLineParts returns the space separated parts of start line
+func (m *RawMessage) startLineParts() []string {
+	return strings.SplitN(strings.TrimSpace(m.StartLine), " ", 3)
+}
+
+// Method returns the method of the request
+func (r *RawRequest) Method() string {
+	return r.startLineParts()[0]
+}
+
+// Target returns the request target (path or absolute url) as is
+func (r *RawRequest) Target() string {
+	if parts := r.startLineParts(); len(parts) > 1 {
+		return parts[1]
+	}
+	return ""
+}
+
+// Proto returns the protocol version of the request (ex: HTTP/1.1)
+func (r *RawRequest) Proto() string {
+	if parts := r.startLineParts(); len(parts) > 2 {
+		return parts[2]
+	}
+	return ""
+}
+
+// SetRequestLine replaces the request line
+func (r *RawRequest) SetRequestLine(method, target, proto string) {
+	r.StartLine = method + " " + target + " " + proto
+}
+
+// ToRequest converts the raw request to a *http.Request.
+// Relative targets are resolved using the Host header and given scheme
+// (http if empty), chunked bodies are decoded.
+func (r *RawRequest) ToRequest(scheme string) (*http.Request, error) {
+	if scheme == "" {
+		scheme = "http"
+	}
+	target := r.Target()
+	host := r.Get("Host")
+	var u *url.URL
+	var err error
+	if strings.Contains(target, "://") {
+		u, err = url.Parse(target)
+	} else {
+		u, err = url.Parse(scheme + "://" + host + target)
+	}
+	if err != nil {
+		return nil, errkit.Wrap(err, "could not parse request target")
+	}
+	body, err := r.DecodedBody()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(r.Method(), u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = r.Header()
+	req.Header.Del("Host")
+	req.Header.Del("Transfer-Encoding")
+	req.Header.Del("Content-Length")
+	if host != "" {
+		req.Host = host
+	}
+	if major, minor, ok := http.ParseHTTPVersion(r.Proto()); ok {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = r.Proto(), major, minor
+	}
+	return req, nil
+}
+
+// NewRawRequest creates a raw request from given http request
+// the request body is consumed and restored
+func NewRawRequest(req *http.Request) (*RawRequest, error) {
+	dump, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRawRequest(dump)
+}
+
+// Proto returns the protocol version of the response (ex: HTTP/1.1)
+func (r *RawResponse) Proto() string {
+	return r.startLineParts()[0]
+}
+
+// StatusCode returns the status code of the response or 0 if invalid
+func (r *RawResponse) StatusCode() int {
+	if parts := r.startLineParts(); len(parts) > 1 {
+		code, _ := strconv.Atoi(parts[1])
+		return code
+	}
+	return 0
+}
+
+// Reason returns the reason phrase of the response
+func (r *RawResponse) Reason() string {
+	if parts := r.startLineParts(); len(parts) > 2 {
+		return parts[2]
+	}
+	return ""
+}
+
+// ToResponse converts the raw response to a *http.Response for given request
+// (which may be nil), chunked bodies are decoded.
+func (r *RawResponse) ToResponse(req *http.Request) (*http.Response, error) {
+	body, err := r.DecodedBody()
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Status:        strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(r.StartLine), r.Proto())),
+		StatusCode:    r.StatusCode(),
+		Proto:         r.Proto(),
+		Header:        r.Header(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if major, minor, ok := http.ParseHTTPVersion(r.Proto()); ok {
+		resp.ProtoMajor, resp.ProtoMinor = major, minor
+	}
+	// body is already decoded
+	resp.Header.Del("Transfer-Encoding")
+	return resp, nil
+}
+
+// NewRawResponse creates a raw response from given http response
+// the response body is consumed and restored
+func NewRawResponse(resp *http.Response) (*RawResponse, error) {
+	dump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRawResponse(dump)
+}
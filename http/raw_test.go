@@ -0,0 +1,98 @@
+package httputil
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawRequest(t *testing.T) {
+	raw := "POST /api?id=1 HTTP/1.1\r\n" +
+		"host: example.com\r\n" +
+		"X-Dup: a\r\n" +
+		"x-dup:b  \r\n" +
+		"this is not a header\n" +
+		"X-Folded: one\r\n" +
+		"\ttwo\r\n" +
+		"Content-Length: 5\r\n" +
+		"\r\n" +
+		"hello"
+
+	req, err := ParseRawRequest([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, raw, req.String(), "round trip must be byte-for-byte")
+
+	require.Equal(t, "POST", req.Method())
+	require.Equal(t, "/api?id=1", req.Target())
+	require.Equal(t, "HTTP/1.1", req.Proto())
+	require.Equal(t, "example.com", req.Get("Host"))
+	require.Equal(t, []string{"a", "b"}, req.Values("x-dup"))
+	require.Equal(t, "one two", req.Get("X-Folded"))
+	require.True(t, req.Headers[3].Malformed)
+	require.Equal(t, "x-dup", req.Headers[2].Name, "casing must be preserved")
+
+	t.Run("Modify", func(t *testing.T) {
+		req, _ := ParseRawRequest([]byte(raw))
+		req.Set("X-Dup", "c")
+		req.Add("X-New", "1")
+		req.SetBody([]byte("hi"))
+		expected := strings.NewReplacer("X-Dup: a\r\nx-dup:b  \r\n", "X-Dup: c\r\n", "Content-Length: 5\r\n", "Content-Length: 2\r\nX-New: 1\r\n", "hello", "hi").Replace(raw)
+		require.Equal(t, expected, req.String())
+	})
+
+	t.Run("To Request", func(t *testing.T) {
+		httpReq, err := req.ToRequest("https")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/api?id=1", httpReq.URL.String())
+		require.Equal(t, "example.com", httpReq.Host)
+		require.Equal(t, []string{"a", "b"}, httpReq.Header.Values("X-Dup"))
+		body, _ := io.ReadAll(httpReq.Body)
+		require.Equal(t, "hello", string(body))
+	})
+
+	t.Run("From Request", func(t *testing.T) {
+		httpReq, _ := http.NewRequest(http.MethodPost, "http://example.com/x", strings.NewReader("data"))
+		rawReq, err := NewRawRequest(httpReq)
+		require.NoError(t, err)
+		require.Equal(t, "POST /x HTTP/1.1", rawReq.StartLine)
+		require.Equal(t, "data", string(rawReq.Body))
+		body, _ := io.ReadAll(httpReq.Body)
+		require.Equal(t, "data", string(body), "body must be restored")
+	})
+
+	_, err = ParseRawRequest([]byte("\r\n"))
+	require.Error(t, err)
+}
+
+func TestRawResponse(t *testing.T) {
+	raw := "HTTP/1.1 200 OK\n" +
+		"Server: test\n" +
+		"Transfer-Encoding: chunked\n" +
+		"\n" +
+		"5\r\nhello\r\n0\r\n\r\n"
+
+	resp, err := ParseRawResponse([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, raw, string(resp.Bytes()))
+	require.Equal(t, 200, resp.StatusCode())
+	require.Equal(t, "OK", resp.Reason())
+
+	httpResp, err := resp.ToResponse(nil)
+	require.NoError(t, err)
+	require.Equal(t, "200 OK", httpResp.Status)
+	require.Empty(t, httpResp.Header.Get("Transfer-Encoding"))
+	body, _ := io.ReadAll(httpResp.Body)
+	require.Equal(t, "hello", string(body))
+
+	httpResp, _ = resp.ToResponse(nil)
+	back, err := NewRawResponse(httpResp)
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/1.1 200 OK", back.StartLine)
+	require.Equal(t, "hello", string(back.Body))
+
+	_, err = ParseRawResponse([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.Error(t, err)
+}
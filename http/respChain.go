@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash"
 	"net/http"
 	"sync"
 
@@ -219,16 +220,36 @@ func resetBuffer() {
 type ResponseChain struct {
 	headers     *bytes.Buffer
 	body        *bytes.Buffer
+	rawBody     *bytes.Buffer // body before charset transcoding (optional)
+	charset     Charset
+	stream      *spillWriter // streaming mode (optional)
+	newHash     func() hash.Hash
+	hash        hash.Hash
 	resp        *http.Response
 	reloaded    bool // if response was reloaded to its previous redirect
 	maxBodySize int64
 }
 
+// ResponseChainOption is a configuration option for the ResponseChain
+type ResponseChainOption func(*ResponseChain)
+
+// WithRawBody keeps the original (decompressed but not transcoded) body
+// bytes alongside the utf-8 normalized body.
+//
+// See [ResponseChain.RawBodyBytes].
+func WithRawBody() ResponseChainOption {
+	return func(r *ResponseChain) {
+		if r.rawBody == nil {
+			r.rawBody = getBuffer()
+		}
+	}
+}
+
 // NewResponseChain creates a new response chain for a http request
 // with a maximum body size.
 //
 // If maxBody is less than or equal to zero, it defaults to [DefaultMaxBodySize].
-func NewResponseChain(resp *http.Response, maxBody int64) *ResponseChain {
+func NewResponseChain(resp *http.Response, maxBody int64, opts ...ResponseChainOption) *ResponseChain {
 	if maxBody <= 0 {
 		maxBody = int64(DefaultMaxBodySize)
 	}
@@ -237,12 +258,16 @@ func NewResponseChain(resp *http.Response, maxBody int64) *ResponseChain {
 		resp.Body = http.MaxBytesReader(nil, resp.Body, maxBody)
 	}
 
-	return &ResponseChain{
+	r := &ResponseChain{
 		headers:     getBuffer(),
 		body:        getBuffer(),
 		resp:        resp,
 		maxBodySize: maxBody,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Headers returns the current response headers buffer in the chain.
@@ -277,7 +302,8 @@ func (r *ResponseChain) Body() *bytes.Buffer {
 
 // BodyBytes returns the current response body as byte slice in the chain.
 //
-// The returned slice is valid only until Close() is called.
+// In streaming mode (see [WithStreaming]) only the in-memory prefix of the
+// body is returned. The returned slice is valid only until Close() is called.
 func (r *ResponseChain) BodyBytes() []byte {
 	return r.body.Bytes()
 }
@@ -289,6 +315,24 @@ func (r *ResponseChain) BodyString() string {
 	return r.body.String()
 }
 
+// RawBodyBytes returns the current response body before charset transcoding.
+//
+// It is only available if the chain was created with [WithRawBody], otherwise
+// it returns [ResponseChain.BodyBytes]. The returned slice is valid only until
+// Close() is called.
+func (r *ResponseChain) RawBodyBytes() []byte {
+	if r.rawBody == nil || !r.charset.NeedsTranscoding() {
+		return r.BodyBytes()
+	}
+	return r.rawBody.Bytes()
+}
+
+// Charset returns the charset of the current response body detected while
+// normalizing it to utf-8.
+func (r *ResponseChain) Charset() Charset {
+	return r.charset
+}
+
 // FullResponse returns a new buffer containing headers+body.
 //
 // Warning: The caller is responsible for managing the returned buffer's
@@ -345,7 +389,7 @@ func (r *ResponseChain) Fill() error {
 	// load headers
 	err := DumpResponseIntoBuffer(r.resp, false, r.headers)
 	if err != nil {
-		return fmt.Errorf("error dumping response headers: %s", err)
+		return fmt.Errorf("error dumping response headers: %w", err)
 	}
 
 	if r.resp.StatusCode != http.StatusSwitchingProtocols && !r.reloaded {
@@ -361,7 +405,7 @@ func (r *ResponseChain) Fill() error {
 		// load body
 		err = readNNormalizeRespBody(r, r.body)
 		if err != nil {
-			return fmt.Errorf("error reading response body: %s", err)
+			return fmt.Errorf("error reading response body: %w", err)
 		}
 
 		// response body should not be used anymore
@@ -383,6 +427,15 @@ func (r *ResponseChain) Close() {
 		putBuffer(r.body)
 		r.body = nil
 	}
+
+	if r.rawBody != nil {
+		putBuffer(r.rawBody)
+		r.rawBody = nil
+	}
+
+	if r.stream != nil {
+		r.stream.close()
+	}
 }
 
 // Has returns true if the response chain has a response
@@ -408,4 +461,14 @@ func (r *ResponseChain) Response() *http.Response {
 func (r *ResponseChain) reset() {
 	r.headers.Reset()
 	r.body.Reset()
+	if r.rawBody != nil {
+		r.rawBody.Reset()
+	}
+	r.charset = Charset{}
+	if r.stream != nil {
+		r.stream.reset(r.body)
+	}
+	if r.newHash != nil {
+		r.hash = r.newHash()
+	}
 }
@@ -0,0 +1,168 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+
+	"github.com/projectdiscovery/utils/buffer"
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+const (
+	// DefaultStreamMemoryLimit is the default number of body bytes kept in
+	// memory in streaming mode. Bytes beyond it are only stored on disk.
+	DefaultStreamMemoryLimit = 64 * 1024 // 64 KB
+)
+
+// WithStreaming enables streaming mode for very large bodies (downloads, crawls).
+//
+// Only the first memLimit bytes of the body are kept in memory (available via
+// [ResponseChain.BodyBytes] for matchers) while bodies larger than memLimit are
+// spilled to a temporary file on disk. The whole body is accessible via
+// [ResponseChain.BodyReader] and limited by the maxBody of the chain instead.
+//
+// memLimit is capped below the large buffer threshold so that streaming chains
+// never hold large pooled buffers. If memLimit is less than or equal to zero,
+// it defaults to [DefaultStreamMemoryLimit]. The body is hashed using sha256
+// unless another hash is configured with [WithBodyHash].
+func WithStreaming(memLimit int64) ResponseChainOption {
+	return func(r *ResponseChain) {
+		if memLimit <= 0 {
+			memLimit = DefaultStreamMemoryLimit
+		}
+		r.stream = &spillWriter{memLimit: int(min(memLimit, largeBufferThreshold-1))}
+		if r.newHash == nil {
+			r.newHash = sha256.New
+		}
+	}
+}
+
+// WithBodyHash computes given hash incrementally while reading the body.
+//
+// See [ResponseChain.BodyHash].
+func WithBodyHash(newHash func() hash.Hash) ResponseChainOption {
+	return func(r *ResponseChain) {
+		r.newHash = newHash
+	}
+}
+
+// BodyReader returns a reader of the whole current response body.
+//
+// In streaming mode bodies larger than the memory limit are read from disk,
+// otherwise the in-memory body is returned. The reader must be closed and is
+// valid only until Close() is called.
+func (r *ResponseChain) BodyReader() (io.ReadSeekCloser, error) {
+	if r.stream != nil && r.stream.disk != nil {
+		return r.stream.disk.Reader()
+	}
+	if r.body == nil {
+		return nil, errkit.New("response chain is closed")
+	}
+	return nopSeekCloser{bytes.NewReader(r.body.Bytes())}, nil
+}
+
+// BodySize returns the size of the whole current response body
+// which may be larger than BodyBytes() in streaming mode.
+func (r *ResponseChain) BodySize() int64 {
+	if r.stream != nil {
+		return r.stream.size
+	}
+	if r.body == nil {
+		return 0
+	}
+	return int64(r.body.Len())
+}
+
+// IsSpilled returns true if the current response body was spilled to disk
+func (r *ResponseChain) IsSpilled() bool {
+	return r.stream != nil && r.stream.disk != nil
+}
+
+// BodyHash returns the hash of the whole current response body
+// or nil if hashing is not enabled (see [WithBodyHash] and [WithStreaming]).
+func (r *ResponseChain) BodyHash() []byte {
+	if r.hash == nil {
+		return nil
+	}
+	return r.hash.Sum(nil)
+}
+
+// spillWriter keeps the first memLimit bytes in memory
+// and spills the whole body to disk once it exceeds the limit
+type spillWriter struct {
+	mem      *bytes.Buffer
+	memLimit int
+	disk     *buffer.DiskBuffer
+	size     int64
+}
+
+// reset prepares the writer for given memory buffer and removes spilled data
+func (s *spillWriter) reset(mem *bytes.Buffer) {
+	s.close()
+	s.mem = mem
+	s.size = 0
+}
+
+func (s *spillWriter) Write(p []byte) (int, error) {
+	if s.disk == nil && s.mem.Len()+len(p) > s.memLimit {
+		disk, err := buffer.New()
+		if err != nil {
+			return 0, errkit.Wrap(err, "could not create disk buffer")
+		}
+		if _, err := disk.Write(s.mem.Bytes()); err != nil {
+			disk.Close()
+			return 0, err
+		}
+		s.disk = disk
+	}
+	if remaining := s.memLimit - s.mem.Len(); remaining > 0 {
+		s.mem.Write(p[:min(len(p), remaining)])
+	}
+	if s.disk != nil {
+		if _, err := s.disk.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	s.size += int64(len(p))
+	return len(p), nil
+}
+
+// ReadFrom reads given reader in chunks until EOF
+func (s *spillWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	chunkPtr := chunkPool.Get().(*[]byte)
+	defer chunkPool.Put(chunkPtr)
+	chunk := *chunkPtr
+
+	for {
+		nr, readErr := r.Read(chunk)
+		if nr > 0 {
+			nw, writeErr := s.Write(chunk[:nr])
+			n += int64(nw)
+			if writeErr != nil {
+				return n, writeErr
+			}
+		}
+		if readErr != nil {
+			if readErr == io.EOF {
+				return n, nil
+			}
+			return n, readErr
+		}
+	}
+}
+
+func (s *spillWriter) close() {
+	if s.disk != nil {
+		s.disk.Close()
+		s.disk = nil
+	}
+}
+
+// nopSeekCloser adds a no-op Close method to a io.ReadSeeker
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
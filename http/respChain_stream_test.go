@@ -0,0 +1,91 @@
+package httputil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cespare/xxhash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseChainStreaming(t *testing.T) {
+	newResp := func(body []byte) *http.Response {
+		return &http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Header:     http.Header{"Content-Type": []string{"application/octet-stream"}},
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+		}
+	}
+
+	t.Run("Spill To Disk", func(t *testing.T) {
+		body := bytes.Repeat([]byte("0123456789abcdef"), 1024*1024) // 16 MB
+		rc := NewResponseChain(newResp(body), 32*1024*1024, WithStreaming(1024))
+		// buffers taken from the pool may be large ones left by other tests
+		semLen := len(largeBufferSem)
+		require.NoError(t, rc.Fill())
+		require.True(t, rc.IsSpilled())
+		require.Equal(t, body[:1024], rc.BodyBytes())
+		require.Equal(t, int64(len(body)), rc.BodySize())
+		sum := sha256.Sum256(body)
+		require.Equal(t, sum[:], rc.BodyHash())
+		require.Equal(t, semLen, len(largeBufferSem))
+
+		reader, err := rc.BodyReader()
+		require.NoError(t, err)
+		_, err = reader.Seek(int64(len(body)-16), io.SeekStart)
+		require.NoError(t, err)
+		tail, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "0123456789abcdef", string(tail))
+		require.NoError(t, reader.Close())
+
+		rc.Close()
+		_, err = rc.BodyReader()
+		require.Error(t, err, "disk buffer must be removed on close")
+	})
+
+	t.Run("Small Body In Memory", func(t *testing.T) {
+		rc := NewResponseChain(newResp([]byte("small")), -1, WithStreaming(0), WithBodyHash(func() hash.Hash { return xxhash.New() }))
+		require.NoError(t, rc.Fill())
+		require.False(t, rc.IsSpilled())
+		require.Equal(t, "small", rc.BodyString())
+		h := xxhash.New()
+		_, _ = h.Write([]byte("small"))
+		require.Equal(t, h.Sum(nil), rc.BodyHash())
+
+		reader, err := rc.BodyReader()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, "small", string(data))
+		rc.Close()
+	})
+
+	t.Run("Invalid Gzip Fallback Is Hashed", func(t *testing.T) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write([]byte("hello"))
+		_ = gw.Close()
+		// trailing data larger than the gzip read buffer is read from the original body
+		buf.Write(bytes.Repeat([]byte("not gzip "), 16*1024))
+
+		resp := newResp(buf.Bytes())
+		resp.Header.Set("Content-Encoding", "gzip")
+		rc := NewResponseChain(resp, -1, WithBodyHash(sha256.New))
+		require.NoError(t, rc.Fill())
+		require.True(t, strings.HasPrefix(rc.BodyString(), "hello"))
+		require.Greater(t, len(rc.BodyBytes()), len("hello"))
+		sum := sha256.Sum256(rc.BodyBytes())
+		require.Equal(t, sum[:], rc.BodyHash())
+		rc.Close()
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+
+	mapsutil "github.com/projectdiscovery/utils/maps"
 )
 
 var (
@@ -22,6 +24,9 @@ func DumpResponseIntoBuffer(resp *http.Response, body bool, buff *bytes.Buffer)
 	if resp == nil {
 		return fmt.Errorf("response is nil")
 	}
+	if resp.ProtoMajor >= 2 {
+		return dumpHTTP1StyleIntoBuffer(resp, body, buff)
+	}
 	save := resp.Body
 	savecl := resp.ContentLength
 
@@ -50,6 +55,21 @@ func DumpResponseIntoBuffer(resp *http.Response, body bool, buff *bytes.Buffer)
 	return
 }
 
+// dumpHTTP1StyleIntoBuffer dumps a http/2 or http/3 response using http/1
+// text framing (see mapsutil.WriteHTTP1Style)
+func dumpHTTP1StyleIntoBuffer(resp *http.Response, body bool, buff *bytes.Buffer) error {
+	if !body || resp.Body == nil {
+		return mapsutil.WriteHTTP1Style(resp, nil, buff)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return mapsutil.WriteHTTP1Style(resp, data, buff)
+}
+
 // DrainResponseBody drains the response body and closes it.
 //
 // This reads and discards up to MaxBodyRead bytes to check for any remaining
@@ -0,0 +1,391 @@
+package iputil
+
+import (
+	"math/big"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/projectdiscovery/utils/errkit"
+)
+
+var (
+	// mapped4Start and mapped4End are the bounds of ipv4-mapped ipv6 addresses
+	mapped4Start = addrToUint128(netip.MustParseAddr("::ffff:0.0.0.0"))
+	mapped4End   = addrToUint128(netip.MustParseAddr("::ffff:255.255.255.255"))
+)
+
+// ipRange is an inclusive range of addresses of the same family
+type ipRange struct {
+	start, end uint128
+}
+
+// size returns the number of addresses of the range minus one
+func (r ipRange) size() uint128 {
+	return r.end.sub(r.start)
+}
+
+// CIDRSet is a set of ipv4 and ipv6 addresses stored as sorted, non
+// overlapping ranges. It supports adding and removing ips, cidrs and ranges,
+// aggregation into the minimal list of prefixes, O(log n) containment checks,
+// counting and lazy iteration without materializing addresses.
+// IPv4-mapped ipv6 addresses (::ffff:0:0/96) are treated as ipv4 addresses.
+// It is safe for concurrent use.
+type CIDRSet struct {
+	mu sync.RWMutex
+	v4 []ipRange
+	v6 []ipRange
+	// dirty is true if added ranges were not sorted and merged yet
+	dirty bool
+}
+
+// NewCIDRSet creates a set containing given ips, cidrs or ranges (see Add)
+func NewCIDRSet(items ...string) (*CIDRSet, error) {
+	s := &CIDRSet{}
+	for _, item := range items {
+		if err := s.Add(item); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Add adds an ip (ex: 192.168.1.1), a cidr (ex: 10.0.0.0/8) or an inclusive
+// range of ips (ex: 10.0.0.1-10.0.0.10) to the set
+func (s *CIDRSet) Add(item string) error {
+	from, to, err := parseCIDRSetItem(item)
+	if err != nil {
+		return err
+	}
+	return s.AddRange(from, to)
+}
+
+// AddPrefix adds all addresses of a prefix to the set
+func (s *CIDRSet) AddPrefix(prefix netip.Prefix) error {
+	if !prefix.IsValid() {
+		return errkit.New("invalid cidr", "cidr", prefix)
+	}
+	from, to := prefixRange(prefix)
+	return s.AddRange(from, to)
+}
+
+// AddRange adds the inclusive range of addresses to the set
+func (s *CIDRSet) AddRange(from, to netip.Addr) error {
+	v4, v6, err := newIPRanges(from, to)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// ranges are merged lazily so that adding many items is O(n log n)
+	s.v4 = append(s.v4, v4...)
+	s.v6 = append(s.v6, v6...)
+	s.dirty = true
+	return nil
+}
+
+// Merge adds all addresses of other to the set
+func (s *CIDRSet) Merge(other *CIDRSet) {
+	v4, v6 := other.ranges()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.v4 = append(s.v4, v4...)
+	s.v6 = append(s.v6, v6...)
+	s.dirty = true
+}
+
+// Remove removes an ip, a cidr or a range of ips (see Add) from the set
+func (s *CIDRSet) Remove(item string) error {
+	from, to, err := parseCIDRSetItem(item)
+	if err != nil {
+		return err
+	}
+	return s.RemoveRange(from, to)
+}
+
+// RemovePrefix removes all addresses of a prefix from the set
+func (s *CIDRSet) RemovePrefix(prefix netip.Prefix) error {
+	if !prefix.IsValid() {
+		return errkit.New("invalid cidr", "cidr", prefix)
+	}
+	from, to := prefixRange(prefix)
+	return s.RemoveRange(from, to)
+}
+
+// RemoveRange removes the inclusive range of addresses from the set
+func (s *CIDRSet) RemoveRange(from, to netip.Addr) error {
+	v4, v6, err := newIPRanges(from, to)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.normalize()
+	s.v4 = subtractRanges(s.v4, v4)
+	s.v6 = subtractRanges(s.v6, v6)
+	return nil
+}
+
+// Exclude removes all addresses of other from the set
+func (s *CIDRSet) Exclude(other *CIDRSet) {
+	v4, v6 := other.ranges()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.normalize()
+	s.v4 = subtractRanges(s.v4, v4)
+	s.v6 = subtractRanges(s.v6, v6)
+}
+
+// Contains returns true if the ip is in the set
+func (s *CIDRSet) Contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return s.ContainsAddr(addr)
+}
+
+// ContainsAddr returns true if the address is in the set
+func (s *CIDRSet) ContainsAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	value := addrToUint128(addr)
+	s.rlock()
+	defer s.mu.RUnlock()
+	ranges := s.v6
+	if addr.Is4() {
+		ranges = s.v4
+	}
+	idx := sort.Search(len(ranges), func(i int) bool {
+		return ranges[i].end.cmp(value) >= 0
+	})
+	return idx < len(ranges) && ranges[idx].start.cmp(value) <= 0
+}
+
+// Prefixes returns the minimal list of prefixes covering the set
+// (ipv4 prefixes first, sorted by address). IPv4-mapped addresses are
+// returned as ipv4 prefixes, so ipv6 ranges spanning ::ffff:0:0/96 are
+// split around it (ex: ::/0 is returned as 0.0.0.0/0 and ~96 ipv6 prefixes).
+func (s *CIDRSet) Prefixes() []netip.Prefix {
+	v4, v6 := s.ranges()
+	var prefixes []netip.Prefix
+	for _, r := range v4 {
+		prefixes = appendRangePrefixes(prefixes, r, true)
+	}
+	for _, r := range v6 {
+		prefixes = appendRangePrefixes(prefixes, r, false)
+	}
+	return prefixes
+}
+
+// Count returns the number of addresses in the set
+func (s *CIDRSet) Count() *big.Int {
+	v4, v6 := s.ranges()
+	total := new(big.Int)
+	for _, ranges := range [][]ipRange{v4, v6} {
+		for _, r := range ranges {
+			total.Add(total, r.size().big())
+			total.Add(total, big.NewInt(1))
+		}
+	}
+	return total
+}
+
+// IsEmpty returns true if the set contains no address
+func (s *CIDRSet) IsEmpty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.v4) == 0 && len(s.v6) == 0
+}
+
+// String returns the comma separated prefixes of the set
+func (s *CIDRSet) String() string {
+	prefixes := s.Prefixes()
+	items := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		items = append(items, prefix.String())
+	}
+	return strings.Join(items, ",")
+}
+
+// rlock acquires the read lock once added ranges are sorted and merged
+func (s *CIDRSet) rlock() {
+	for {
+		s.mu.RLock()
+		if !s.dirty {
+			return
+		}
+		s.mu.RUnlock()
+		s.mu.Lock()
+		s.normalize()
+		s.mu.Unlock()
+	}
+}
+
+// ranges returns a copy of the normalized ranges of the set
+func (s *CIDRSet) ranges() ([]ipRange, []ipRange) {
+	s.rlock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.v4), slices.Clone(s.v6)
+}
+
+// normalize sorts and merges overlapping or adjacent ranges (lock must be held)
+func (s *CIDRSet) normalize() {
+	if !s.dirty {
+		return
+	}
+	s.v4 = mergeRanges(s.v4)
+	s.v6 = mergeRanges(s.v6)
+	s.dirty = false
+}
+
+func mergeRanges(ranges []ipRange) []ipRange {
+	if len(ranges) < 2 {
+		return ranges
+	}
+	slices.SortFunc(ranges, func(a, b ipRange) int {
+		return a.start.cmp(b.start)
+	})
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if last.end == maxUint128 || r.start.cmp(last.end.addOne()) <= 0 {
+			if r.end.cmp(last.end) > 0 {
+				last.end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// subtractRanges returns the ranges of a not covered by b (both normalized)
+func subtractRanges(a, b []ipRange) []ipRange {
+	if len(a) == 0 || len(b) == 0 {
+		return a
+	}
+	result := make([]ipRange, 0, len(a))
+	j := 0
+	for _, r := range a {
+		// skip exclusions before the range
+		for j < len(b) && b[j].end.cmp(r.start) < 0 {
+			j++
+		}
+		remaining := true
+		for k := j; k < len(b) && b[k].start.cmp(r.end) <= 0; k++ {
+			if b[k].start.cmp(r.start) > 0 {
+				result = append(result, ipRange{start: r.start, end: b[k].start.subOne()})
+			}
+			if b[k].end.cmp(r.end) >= 0 {
+				remaining = false
+				break
+			}
+			r.start = b[k].end.addOne()
+		}
+		if remaining {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// appendRangePrefixes appends the minimal prefixes covering the range
+func appendRangePrefixes(prefixes []netip.Prefix, r ipRange, is4 bool) []netip.Prefix {
+	addrBits := 128
+	if is4 {
+		addrBits = 32
+	}
+	start := r.start
+	for {
+		remaining := r.end.sub(start)
+		// the largest block aligned on start and fitting in the range
+		blockBits := min(start.trailingZeros(), addrBits)
+		if remaining != maxUint128 {
+			blockBits = min(blockBits, remaining.addOne().bitLen()-1)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(start.addr(is4), addrBits-blockBits))
+		blockEnd := start.add(mask128(uint(blockBits)))
+		if blockEnd.cmp(r.end) >= 0 {
+			return prefixes
+		}
+		start = blockEnd.addOne()
+	}
+}
+
+// newIPRanges validates the inclusive range of addresses and returns its
+// ipv4 and ipv6 ranges. IPv4-mapped ipv6 addresses (::ffff:0:0/96) are
+// stored as ipv4 so ipv6 ranges overlapping that block are split.
+func newIPRanges(from, to netip.Addr) ([]ipRange, []ipRange, error) {
+	if !from.IsValid() || !to.IsValid() {
+		return nil, nil, errkit.New("invalid ip range", "from", from, "to", to)
+	}
+	if from.Is4() || to.Is4() {
+		from, to = from.Unmap(), to.Unmap()
+		if !from.Is4() || !to.Is4() {
+			return nil, nil, errkit.New("ip range with mixed address families", "from", from, "to", to)
+		}
+	}
+	r := ipRange{start: addrToUint128(from), end: addrToUint128(to)}
+	if r.start.cmp(r.end) > 0 {
+		return nil, nil, errkit.New("ip range start is after end", "from", from, "to", to)
+	}
+	if from.Is4() {
+		return []ipRange{r}, nil, nil
+	}
+	var v4, v6 []ipRange
+	if r.start.cmp(mapped4Start) < 0 {
+		v6 = append(v6, ipRange{start: r.start, end: min128(r.end, mapped4Start.subOne())})
+	}
+	if r.start.cmp(mapped4End) <= 0 && r.end.cmp(mapped4Start) >= 0 {
+		start, end := max128(r.start, mapped4Start), min128(r.end, mapped4End)
+		v4 = append(v4, ipRange{start: start.sub(mapped4Start), end: end.sub(mapped4Start)})
+	}
+	if r.end.cmp(mapped4End) > 0 {
+		v6 = append(v6, ipRange{start: max128(r.start, mapped4End.addOne()), end: r.end})
+	}
+	return v4, v6, nil
+}
+
+// prefixRange returns the first and last address of a prefix
+func prefixRange(prefix netip.Prefix) (netip.Addr, netip.Addr) {
+	prefix = prefix.Masked()
+	is4 := prefix.Addr().Is4()
+	addrBits := 128
+	if is4 {
+		addrBits = 32
+	}
+	start := addrToUint128(prefix.Addr())
+	end := start.or(mask128(uint(addrBits - prefix.Bits())))
+	return start.addr(is4), end.addr(is4)
+}
+
+// parseCIDRSetItem parses an ip, a cidr or a range of ips
+func parseCIDRSetItem(item string) (netip.Addr, netip.Addr, error) {
+	item = strings.TrimSpace(item)
+	if strings.Contains(item, "/") {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, errkit.New("invalid cidr", "cidr", item)
+		}
+		from, to := prefixRange(prefix)
+		return from, to, nil
+	}
+	if first, last, ok := strings.Cut(item, "-"); ok {
+		from, err1 := netip.ParseAddr(strings.TrimSpace(first))
+		to, err2 := netip.ParseAddr(strings.TrimSpace(last))
+		if err1 != nil || err2 != nil {
+			return netip.Addr{}, netip.Addr{}, errkit.New("invalid ip range", "range", item)
+		}
+		return from, to, nil
+	}
+	addr, err := netip.ParseAddr(item)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, errkit.New("invalid ip, cidr or range", "item", item)
+	}
+	return addr, addr, nil
+}
@@ -0,0 +1,143 @@
+package iputil
+
+import (
+	"iter"
+	"net/netip"
+	"sort"
+)
+
+// feistelRounds is the number of rounds of the index permutation
+const feistelRounds = 4
+
+// IterateOption configures the iteration over a CIDRSet
+type IterateOption func(*iterateConfig)
+
+type iterateConfig struct {
+	random bool
+	seed   uint64
+}
+
+// WithRandomOrder yields the addresses in a pseudo random order derived from
+// seed (the same seed yields the same order), spreading scans across
+// networks. Each address is still yielded exactly once and no address is
+// materialized: the order is computed with a permutation of address indexes.
+func WithRandomOrder(seed int64) IterateOption {
+	return func(cfg *iterateConfig) {
+		cfg.random = true
+		cfg.seed = uint64(seed)
+	}
+}
+
+// All returns an iterator over the addresses of the set (ipv4 addresses
+// first). The iterator works on a snapshot of the set taken when the
+// iteration starts.
+//
+//	for addr := range set.All(iputil.WithRandomOrder(time.Now().UnixNano())) {
+//		...
+//	}
+func (s *CIDRSet) All(opts ...IterateOption) iter.Seq[netip.Addr] {
+	var cfg iterateConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(yield func(netip.Addr) bool) {
+		v4, v6 := s.ranges()
+		if cfg.random {
+			_ = yieldRandom(v4, true, cfg.seed, yield) && yieldRandom(v6, false, cfg.seed, yield)
+			return
+		}
+		_ = yieldSequential(v4, true, yield) && yieldSequential(v6, false, yield)
+	}
+}
+
+func yieldSequential(ranges []ipRange, is4 bool, yield func(netip.Addr) bool) bool {
+	for _, r := range ranges {
+		for value := r.start; ; value = value.addOne() {
+			if !yield(value.addr(is4)) {
+				return false
+			}
+			if value == r.end {
+				break
+			}
+		}
+	}
+	return true
+}
+
+func yieldRandom(ranges []ipRange, is4 bool, seed uint64, yield func(netip.Addr) bool) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	// offsets[i] is the index of the first address of ranges[i]
+	offsets := make([]uint128, len(ranges))
+	var next uint128
+	for i, r := range ranges {
+		offsets[i] = next
+		next = next.add(r.size()).addOne()
+	}
+	last := ranges[len(ranges)-1]
+	maxIndex := offsets[len(offsets)-1].add(last.size())
+
+	perm := newIndexPermutation(maxIndex, seed)
+	for index := (uint128{}); ; index = index.addOne() {
+		permuted := perm.at(index)
+		i := sort.Search(len(offsets), func(i int) bool {
+			return offsets[i].cmp(permuted) > 0
+		}) - 1
+		value := ranges[i].start.add(permuted.sub(offsets[i]))
+		if !yield(value.addr(is4)) {
+			return false
+		}
+		if index == maxIndex {
+			return true
+		}
+	}
+}
+
+// indexPermutation is a bijection of [0, maxIndex] built from a balanced
+// feistel network on the smallest even number of bits covering maxIndex,
+// restricted to the domain by cycle walking (at most 4 steps on average)
+type indexPermutation struct {
+	maxIndex uint128
+	halfBits uint
+	halfMask uint128
+	keys     [feistelRounds]uint64
+}
+
+func newIndexPermutation(maxIndex uint128, seed uint64) *indexPermutation {
+	halfBits := uint(max(maxIndex.bitLen()+1, 2) / 2)
+	p := &indexPermutation{maxIndex: maxIndex, halfBits: halfBits, halfMask: mask128(halfBits)}
+	state := seed
+	for i := range p.keys {
+		state = splitmix64(state)
+		p.keys[i] = state
+	}
+	return p
+}
+
+func (p *indexPermutation) at(index uint128) uint128 {
+	for {
+		index = p.encrypt(index)
+		if index.cmp(p.maxIndex) <= 0 {
+			return index
+		}
+	}
+}
+
+func (p *indexPermutation) encrypt(value uint128) uint128 {
+	// halves are at most 64 bits
+	left, right := value.rsh(p.halfBits).lo, value.and(p.halfMask).lo
+	mask := p.halfMask.lo
+	for _, key := range p.keys {
+		left, right = right, (left^splitmix64(right^key))&mask
+	}
+	return uint128{lo: left}.lsh(p.halfBits).or(uint128{lo: right})
+}
+
+// splitmix64 is the finalizer of the splitmix64 generator
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
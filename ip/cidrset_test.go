@@ -0,0 +1,205 @@
+package iputil
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIDRSetAggregation(t *testing.T) {
+	set, err := NewCIDRSet(
+		"10.0.0.0/25",
+		"10.0.0.128/25",
+		"10.0.1.0/24",
+		"10.0.0.5",
+		"192.168.1.1-192.168.1.6",
+		"2001:db8::/33",
+		"2001:db8:8000::/33",
+		"::ffff:172.16.0.1",
+	)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/23"),
+		netip.MustParsePrefix("172.16.0.1/32"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+		netip.MustParsePrefix("192.168.1.2/31"),
+		netip.MustParsePrefix("192.168.1.4/31"),
+		netip.MustParsePrefix("192.168.1.6/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, set.Prefixes())
+	require.Equal(t, "10.0.0.0/23,172.16.0.1/32,192.168.1.1/32,192.168.1.2/31,192.168.1.4/31,192.168.1.6/32,2001:db8::/32", set.String())
+
+	expected := new(big.Int).Lsh(big.NewInt(1), 96)
+	expected.Add(expected, big.NewInt(512+1+6))
+	require.Equal(t, expected, set.Count())
+
+	for _, item := range []string{"", "10.0.0.0/33", "10.0.0.1-", "10.0.0.2-10.0.0.1", "10.0.0.1-::1", "not-an-ip"} {
+		require.Error(t, set.Add(item), item)
+	}
+}
+
+func TestCIDRSetContains(t *testing.T) {
+	set, err := NewCIDRSet("10.0.0.0/8", "192.168.0.0/16", "2001:db8::/32")
+	require.NoError(t, err)
+
+	for _, ip := range []string{"10.0.0.0", "10.255.255.255", "192.168.10.10", "::ffff:10.1.1.1", "2001:db8::1"} {
+		require.True(t, set.Contains(ip), ip)
+	}
+	for _, ip := range []string{"9.255.255.255", "11.0.0.0", "172.16.0.1", "2001:db9::1", "::a00:1", "invalid"} {
+		require.False(t, set.Contains(ip), ip)
+	}
+
+	require.NoError(t, set.Remove("10.1.0.0/16"))
+	require.NoError(t, set.Remove("10.0.0.0"))
+	require.NoError(t, set.Remove("192.168.0.0-192.168.255.255"))
+	require.False(t, set.Contains("10.1.2.3"))
+	require.False(t, set.Contains("10.0.0.0"))
+	require.False(t, set.Contains("192.168.1.1"))
+	require.True(t, set.Contains("10.0.0.1"))
+	require.True(t, set.Contains("10.2.0.0"))
+	require.Equal(t, big.NewInt(1<<24-1<<16-1).String(), new(big.Int).Sub(set.Count(), new(big.Int).Lsh(big.NewInt(1), 96)).String())
+}
+
+func TestCIDRSetExcludeMerge(t *testing.T) {
+	set, err := NewCIDRSet("0.0.0.0/0", "::/0")
+	require.NoError(t, err)
+	// ipv4-mapped ipv6 addresses are stored as ipv4
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 128), set.Count())
+	full := set.String()
+	require.True(t, strings.HasPrefix(full, "0.0.0.0/0,::/81,"), full)
+
+	exclude, err := NewCIDRSet("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "0.0.0.0", "255.255.255.255", "::/96")
+	require.NoError(t, err)
+	set.Exclude(exclude)
+	require.False(t, set.Contains("10.1.1.1"))
+	require.False(t, set.Contains("0.0.0.0"))
+	require.False(t, set.Contains("255.255.255.255"))
+	require.False(t, set.Contains("::1"))
+	require.True(t, set.Contains("8.8.8.8"))
+	require.True(t, set.Contains("ffff::1"))
+
+	set.Merge(exclude)
+	require.Equal(t, full, set.String())
+
+	require.NoError(t, set.RemovePrefix(netip.MustParsePrefix("0.0.0.0/0")))
+	require.NoError(t, set.RemovePrefix(netip.MustParsePrefix("::/0")))
+	require.True(t, set.IsEmpty())
+	require.Zero(t, set.Count().Sign())
+	require.Empty(t, set.Prefixes())
+}
+
+func TestCIDRSetMappedIPv4(t *testing.T) {
+	set, err := NewCIDRSet("::/0")
+	require.NoError(t, err)
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 128), set.Count())
+	for _, ip := range []string{"::ffff:1.2.3.4", "1.2.3.4", "::1", "ffff::1"} {
+		require.True(t, set.Contains(ip), ip)
+	}
+
+	// the last address of ::/80 is ipv4-mapped (::ffff:255.255.255.255)
+	set = &CIDRSet{}
+	require.NoError(t, set.Add("::/80"))
+	require.NoError(t, set.AddPrefix(netip.MustParsePrefix("::/80")))
+	require.Equal(t, new(big.Int).Lsh(big.NewInt(1), 48), set.Count())
+	require.True(t, set.Contains("::1"))
+	require.True(t, set.Contains("10.0.0.1"))
+	require.False(t, set.Contains("::1:0:0:0"))
+
+	require.NoError(t, set.RemovePrefix(netip.MustParsePrefix("::ffff:10.0.0.0/104")))
+	require.False(t, set.Contains("10.0.0.1"))
+	require.True(t, set.Contains("11.0.0.1"))
+	require.Error(t, set.AddRange(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")))
+}
+
+func TestCIDRSetInvalidPrefix(t *testing.T) {
+	set, err := NewCIDRSet("10.0.0.0/8")
+	require.NoError(t, err)
+	require.Error(t, set.AddPrefix(netip.Prefix{}))
+	require.Error(t, set.RemovePrefix(netip.Prefix{}))
+	require.Equal(t, big.NewInt(1<<24), set.Count())
+	require.False(t, set.Contains("8.8.8.8"))
+	require.True(t, set.Contains("10.0.0.1"))
+}
+
+func TestCIDRSetAll(t *testing.T) {
+	set, err := NewCIDRSet("192.168.0.0/30", "10.0.0.1-10.0.0.3", "2001:db8::/127")
+	require.NoError(t, err)
+
+	var sequential []string
+	for addr := range set.All() {
+		sequential = append(sequential, addr.String())
+	}
+	require.Equal(t, []string{
+		"10.0.0.1", "10.0.0.2", "10.0.0.3",
+		"192.168.0.0", "192.168.0.1", "192.168.0.2", "192.168.0.3",
+		"2001:db8::", "2001:db8::1",
+	}, sequential)
+
+	var random []string
+	for addr := range set.All(WithRandomOrder(42)) {
+		random = append(random, addr.String())
+	}
+	require.ElementsMatch(t, sequential, random)
+
+	// early stop
+	count := 0
+	for range set.All(WithRandomOrder(1)) {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	require.Equal(t, 2, count)
+
+	// the random order is a permutation of large sets
+	set, err = NewCIDRSet("10.0.0.0/20", "10.1.0.0/22", "10.2.0.7-10.2.3.200")
+	require.NoError(t, err)
+	seen := map[netip.Addr]struct{}{}
+	var first []netip.Addr
+	for addr := range set.All(WithRandomOrder(7)) {
+		require.True(t, set.ContainsAddr(addr))
+		seen[addr] = struct{}{}
+		if len(first) < 16 {
+			first = append(first, addr)
+		}
+	}
+	require.Equal(t, set.Count().Int64(), int64(len(seen)))
+	var again []netip.Addr
+	for addr := range set.All(WithRandomOrder(7)) {
+		again = append(again, addr)
+		if len(again) == 16 {
+			break
+		}
+	}
+	require.Equal(t, first, again)
+	require.NotEqual(t, netip.MustParseAddr("10.0.0.0"), first[0], "order should not be sequential")
+
+	// huge sets are iterated lazily
+	set, err = NewCIDRSet("2000::/3")
+	require.NoError(t, err)
+	count = 0
+	for addr := range set.All(WithRandomOrder(3)) {
+		require.True(t, addr.Is6())
+		if count++; count == 1000 {
+			break
+		}
+	}
+	require.Equal(t, 1000, count)
+}
+
+func BenchmarkCIDRSetContains(b *testing.B) {
+	set := &CIDRSet{}
+	for i := 0; i < 100000; i++ {
+		_ = set.Add(fmt.Sprintf("%d.%d.%d.0/25", 10+i>>16, (i>>8)&0xff, i&0xff))
+	}
+	addr := netip.MustParseAddr("10.128.64.1")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.ContainsAddr(addr)
+	}
+}
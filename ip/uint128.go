@@ -0,0 +1,149 @@
+package iputil
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+	"net/netip"
+)
+
+// uint128 is an unsigned 128 bit integer used to represent both ipv4 and ipv6
+// addresses (and address counts) without allocations
+type uint128 struct {
+	hi, lo uint64
+}
+
+var maxUint128 = uint128{hi: ^uint64(0), lo: ^uint64(0)}
+
+func addrToUint128(addr netip.Addr) uint128 {
+	if addr.Is4() {
+		b := addr.As4()
+		return uint128{lo: uint64(binary.BigEndian.Uint32(b[:]))}
+	}
+	b := addr.As16()
+	return uint128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (u uint128) addr(is4 bool) netip.Addr {
+	if is4 {
+		var b [4]byte
+		binary.BigEndian.PutUint32(b[:], uint32(u.lo))
+		return netip.AddrFrom4(b)
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	return netip.AddrFrom16(b)
+}
+
+func (u uint128) cmp(v uint128) int {
+	switch {
+	case u.hi < v.hi:
+		return -1
+	case u.hi > v.hi:
+		return 1
+	case u.lo < v.lo:
+		return -1
+	case u.lo > v.lo:
+		return 1
+	}
+	return 0
+}
+
+func min128(u, v uint128) uint128 {
+	if u.cmp(v) <= 0 {
+		return u
+	}
+	return v
+}
+
+func max128(u, v uint128) uint128 {
+	if u.cmp(v) >= 0 {
+		return u
+	}
+	return v
+}
+
+// add returns u+v (wrapping on overflow)
+func (u uint128) add(v uint128) uint128 {
+	lo, carry := bits.Add64(u.lo, v.lo, 0)
+	hi, _ := bits.Add64(u.hi, v.hi, carry)
+	return uint128{hi: hi, lo: lo}
+}
+
+// sub returns u-v (wrapping on underflow)
+func (u uint128) sub(v uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	hi, _ := bits.Sub64(u.hi, v.hi, borrow)
+	return uint128{hi: hi, lo: lo}
+}
+
+func (u uint128) addOne() uint128 {
+	return u.add(uint128{lo: 1})
+}
+
+func (u uint128) subOne() uint128 {
+	return u.sub(uint128{lo: 1})
+}
+
+func (u uint128) lsh(n uint) uint128 {
+	switch {
+	case n >= 128:
+		return uint128{}
+	case n >= 64:
+		return uint128{hi: u.lo << (n - 64)}
+	case n == 0:
+		return u
+	}
+	return uint128{hi: u.hi<<n | u.lo>>(64-n), lo: u.lo << n}
+}
+
+func (u uint128) rsh(n uint) uint128 {
+	switch {
+	case n >= 128:
+		return uint128{}
+	case n >= 64:
+		return uint128{lo: u.hi >> (n - 64)}
+	case n == 0:
+		return u
+	}
+	return uint128{hi: u.hi >> n, lo: u.lo>>n | u.hi<<(64-n)}
+}
+
+// mask returns a value with the n low bits set
+func mask128(n uint) uint128 {
+	if n >= 128 {
+		return maxUint128
+	}
+	return uint128{lo: 1}.lsh(n).subOne()
+}
+
+func (u uint128) and(v uint128) uint128 {
+	return uint128{hi: u.hi & v.hi, lo: u.lo & v.lo}
+}
+
+func (u uint128) or(v uint128) uint128 {
+	return uint128{hi: u.hi | v.hi, lo: u.lo | v.lo}
+}
+
+// bitLen returns the minimum number of bits required to represent u
+func (u uint128) bitLen() int {
+	if u.hi != 0 {
+		return 64 + bits.Len64(u.hi)
+	}
+	return bits.Len64(u.lo)
+}
+
+// trailingZeros returns the number of trailing zero bits (128 for zero)
+func (u uint128) trailingZeros() int {
+	if u.lo != 0 {
+		return bits.TrailingZeros64(u.lo)
+	}
+	return 64 + bits.TrailingZeros64(u.hi)
+}
+
+func (u uint128) big() *big.Int {
+	value := new(big.Int).SetUint64(u.hi)
+	value.Lsh(value, 64)
+	return value.Or(value, new(big.Int).SetUint64(u.lo))
+}
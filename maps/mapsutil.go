@@ -2,11 +2,13 @@ package mapsutil
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -50,16 +52,107 @@ func HTTPToMap(resp *http.Response, body, headers string, duration time.Duration
 	m[fmt.Sprintf(format, "all_headers")] = headers
 	m[fmt.Sprintf(format, "body")] = body
 
-	if r, err := httputil.DumpResponse(resp, true); err == nil {
+	if resp.ProtoMajor >= 2 {
+		// http/2 and http/3 responses are dumped using http/1 framing like
+		// httputil.DumpResponseHeadersAndRaw does
+		var raw bytes.Buffer
+		if err := WriteHTTP1Style(resp, []byte(body), &raw); err == nil {
+			m[fmt.Sprintf(format, "raw")] = raw.String()
+		}
+	} else if r, err := httputil.DumpResponse(resp, true); err == nil {
 		m[fmt.Sprintf(format, "raw")] = string(r)
 	}
 
 	// Converts duration to seconds (floating point) for DSL syntax
 	m[fmt.Sprintf(format, "duration")] = duration.Seconds()
 
+	for k, v := range httpProtocolToMap(resp) {
+		m[fmt.Sprintf(format, k)] = v
+	}
+
+	return m
+}
+
+// httpProtocolToMap returns the protocol version, tls state and http/2
+// (or http/3) pseudo headers of the response
+func httpProtocolToMap(resp *http.Response) map[string]interface{} {
+	m := map[string]interface{}{
+		"proto":       resp.Proto,
+		"proto_major": resp.ProtoMajor,
+		"proto_minor": resp.ProtoMinor,
+	}
+	if resp.TLS != nil {
+		m["alpn"] = resp.TLS.NegotiatedProtocol
+		m["tls_version"] = tls.VersionName(resp.TLS.Version)
+		m["tls_cipher_suite"] = tls.CipherSuiteName(resp.TLS.CipherSuite)
+		m["tls_server_name"] = resp.TLS.ServerName
+	}
+	if resp.ProtoMajor < 2 {
+		return m
+	}
+	m["pseudo_status"] = strconv.Itoa(resp.StatusCode)
+	if req := resp.Request; req != nil && req.URL != nil {
+		m["pseudo_method"] = req.Method
+		authority := req.Host
+		if authority == "" {
+			authority = req.URL.Host
+		}
+		m["pseudo_authority"] = authority
+		if req.Method != http.MethodConnect {
+			m["pseudo_scheme"] = req.URL.Scheme
+			m["pseudo_path"] = req.URL.RequestURI()
+		}
+	}
 	return m
 }
 
+// http1StyleExcludeHeaders are headers not written by WriteHTTP1Style. Content-Length
+// is written first (like (*http.Response).Write does) and the others are
+// connection specific headers not valid in http/2 and http/3.
+var http1StyleExcludeHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// WriteHTTP1Style writes a http/2 or http/3 response using http/1 text framing
+// i.e "HTTP/2.0 200 OK" status line followed by Content-Length and the sorted
+// headers. Unlike (*http.Response).Write it never adds framing headers
+// (Connection: close or Transfer-Encoding: chunked) that were not received.
+// If body is not nil it is written after the headers and Content-Length is
+// set to its length when the length of the response was unknown.
+func WriteHTTP1Style(resp *http.Response, body []byte, w io.Writer) error {
+	text := resp.Status
+	if text == "" {
+		text = http.StatusText(resp.StatusCode)
+	} else {
+		// status is "200 OK", strip the code
+		text = strings.TrimPrefix(text, strconv.Itoa(resp.StatusCode)+" ")
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%d.%d %03d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.StatusCode, text)
+
+	switch {
+	case resp.ContentLength >= 0:
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", resp.ContentLength)
+	case body != nil:
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(body))
+	case resp.Header.Get("Content-Length") != "":
+		fmt.Fprintf(&buf, "Content-Length: %s\r\n", resp.Header.Get("Content-Length"))
+	}
+	if err := resp.Header.WriteSubset(&buf, http1StyleExcludeHeaders); err != nil {
+		return err
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
 // HTTPRequestToMap Converts HTTP Request to Matcher Map
 func HTTPRequestToMap(req *http.Request) (map[string]interface{}, error) {
 	m := make(map[string]interface{})
@@ -82,6 +82,28 @@ func TestHTTPToMap(t *testing.T) {
 	require.NotEmpty(t, m)
 }
 
+func TestHTTPToMapHTTP2(t *testing.T) {
+	h2Resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    200,
+		Proto:         "HTTP/2.0",
+		ProtoMajor:    2,
+		Header:        http.Header{"Content-Type": {"text/plain"}},
+		ContentLength: 5,
+		// body already consumed by the caller
+		Body: http.NoBody,
+	}
+	m := HTTPToMap(h2Resp, "hello", "", 0, "")
+	require.Equal(t, "HTTP/2.0 200 OK\r\nContent-Length: 5\r\nContent-Type: text/plain\r\n\r\nhello", m["raw"])
+	require.Equal(t, int64(5), m["content_length"])
+
+	// decoded body of unknown length
+	h2Resp.ContentLength = -1
+	m = HTTPToMap(h2Resp, "hello world", "", 0, "")
+	require.Equal(t, "HTTP/2.0 200 OK\r\nContent-Length: 11\r\nContent-Type: text/plain\r\n\r\nhello world", m["raw"])
+	require.Equal(t, int64(-1), m["content_length"])
+}
+
 func TestHTTPRequestToMap(t *testing.T) {
 	m, err := HTTPRequestToMap(req)
 